@@ -0,0 +1,34 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	if !clock.Now().Equal(start) {
+		t.Errorf("Now = %v, want %v", clock.Now(), start)
+	}
+	if got := clock.Advance(time.Hour); !got.Equal(start.Add(time.Hour)) || !clock.Now().Equal(got) {
+		t.Errorf("Advance = %v and Now = %v, want %v", got, clock.Now(), start.Add(time.Hour))
+	}
+
+	clock.Set(start)
+	if !clock.Now().Equal(start) {
+		t.Errorf("Now after Set = %v, want %v", clock.Now(), start)
+	}
+}
+
+func TestGetClock(t *testing.T) {
+	if _, ok := GetClock(nil).(SystemClock); !ok {
+		t.Error("GetClock(nil) is not the SystemClock")
+	}
+
+	clock := NewFakeClock(time.Time{})
+	if GetClock(clock) != Clock(clock) {
+		t.Error("GetClock did not return the given clock")
+	}
+}
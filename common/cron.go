@@ -0,0 +1,193 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed standard 5 field cron expression: minute hour day-of-month month day-of-week.
+// Fields accept *, numbers, ranges (1-5), lists (1,3,5) and steps (*/15, 0-30/10); month and day-of-week
+// also accept English abbreviations (JAN, MON). The macros @yearly, @monthly, @weekly, @daily and
+// @hourly are supported. As in cron, when both day fields are restricted a day matching either runs.
+type CronSchedule struct {
+	Expression string
+
+	minute, hour, dom, month, dow uint64 // bit i set when value i matches
+	domStar, dowStar              bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{min: 0, max: 59}
+	cronHour   = cronField{min: 0, max: 23}
+	cronDom    = cronField{min: 1, max: 31}
+	cronMonth  = cronField{min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	cronDow = cronField{min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+
+	cronMacros = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// cronSearchYears bounds the search of Next, a schedule like "0 0 30 2 *" never matches
+const cronSearchYears = 5
+
+// ParseCron parses a cron expression
+func ParseCron(expr string) (*CronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, got %d", expr, len(fields))
+	}
+
+	schedule := &CronSchedule{
+		Expression: expr,
+		domStar:    strings.HasPrefix(fields[2], "*"),
+		dowStar:    strings.HasPrefix(fields[4], "*"),
+	}
+
+	var err error
+	for i, target := range []struct {
+		bits  *uint64
+		field cronField
+	}{
+		{&schedule.minute, cronMinute},
+		{&schedule.hour, cronHour},
+		{&schedule.dom, cronDom},
+		{&schedule.month, cronMonth},
+		{&schedule.dow, cronDow},
+	} {
+		if *target.bits, err = parseCronField(fields[i], target.field); err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+	}
+
+	// 7 is an alias of Sunday
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	return schedule, nil
+}
+
+func parseCronField(text string, field cronField) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(text, ",") {
+		step := 1
+		if index := strings.Index(part, "/"); index >= 0 {
+			value, err := strconv.Atoi(part[index+1:])
+			if err != nil || value <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = value
+			part = part[:index]
+		}
+
+		low, high := field.min, field.max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if low, err = parseCronValue(bounds[0], field); err != nil {
+				return 0, err
+			}
+			if high, err = parseCronValue(bounds[1], field); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			value, err := parseCronValue(part, field)
+			if err != nil {
+				return 0, err
+			}
+			low = value
+			if step == 1 {
+				high = value
+			}
+		}
+
+		for i := low; i <= high; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseCronValue(text string, field cronField) (int, error) {
+	if value, ok := field.names[strings.ToUpper(text)]; ok {
+		return value, nil
+	}
+
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", text)
+	}
+	if value < field.min || value > field.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", value, field.min, field.max)
+	}
+	return value, nil
+}
+
+// Next returns the first time strictly after t matching the schedule, in the location of t.
+// The zero time is returned if nothing matches within a few years.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *CronSchedule) matchDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
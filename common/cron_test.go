@@ -0,0 +1,81 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr  string
+		valid bool
+	}{
+		{"* * * * *", true},
+		{"*/15 0-6 1,15 JAN-MAR mon-fri", true},
+		{"0-30/10 * * * 7", true},
+		{"@daily", true},
+		{"@HOURLY", true},
+		{"* * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"* * * 13 *", false},
+		{"* * * * 8", false},
+		{"5-1 * * * *", false},
+		{"*/0 * * * *", false},
+		{"* * * FOO *", false},
+		{"@reboot", false},
+	}
+
+	for _, test := range tests {
+		_, err := ParseCron(test.expr)
+		if (err == nil) != test.valid {
+			t.Errorf("ParseCron(%q) error = %v, want valid %v", test.expr, err, test.valid)
+		}
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	// 2024-01-15 is a Monday
+	from := time.Date(2024, 1, 15, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2024, 1, 16, 9, 30, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * SUN", time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 * *", time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// either day field matches when both are restricted: the 20th or a Wednesday
+		{"0 0 20 * WED", time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseCron(test.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", test.expr, err)
+		}
+		if got := schedule.Next(from); !got.Equal(test.want) {
+			t.Errorf("Next of %q = %v, want %v", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestCronScheduleNextIsStrictlyAfter(t *testing.T) {
+	schedule, err := ParseCron("0 * * * *")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+
+	at := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	if got, want := schedule.Next(at), at.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("Next(%v) = %v, want %v", at, got, want)
+	}
+}
@@ -0,0 +1,221 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRunNotFound        = errors.New("workflow run not found")
+	ErrRunVersionConflict = errors.New("workflow run version conflict")
+	ErrStepInterrupted    = errors.New("step was interrupted and is not idempotent")
+)
+
+// CheckpointStore persists the state of workflow runs. Save is optimistic: the record's Version must
+// match the stored one (0 for a new run), and is incremented on success, otherwise ErrRunVersionConflict
+// is returned and nothing is written. A run is thus only ever continued by one process.
+type CheckpointStore interface {
+	Load(ctx context.Context, runID string) (*RunRecord, error)
+	Save(ctx context.Context, record *RunRecord) error
+	Delete(ctx context.Context, runID string) error
+	List(ctx context.Context, status string) ([]*RunRecord, error) // all statuses when status is empty
+}
+
+// RunRecord is the checkpoint of a workflow run, saved before and after every step and every attempt
+type RunRecord struct {
+	RunID       string                    `json:"run_id"`
+	Workflow    string                    `json:"workflow"`
+	Status      string                    `json:"status"`
+	CurrentStep string                    `json:"current_step"` // step running or to run next, empty at the end; in DAG mode the running steps, comma separated
+	StepStatus  map[string]WorkStepStatus `json:"step_status"`
+	Path        []string                  `json:"path"`
+	History     []*StepAttempt            `json:"history"`
+	Context     json.RawMessage           `json:"context"` // JSON of the context given to the steps
+	Error       string                    `json:"error"`
+	Version     int64                     `json:"version"`
+	UpdatedAt   time.Time                 `json:"updated_at"`
+}
+
+// clone copies the record so stores never share memory with their callers
+func (r *RunRecord) clone() *RunRecord {
+	result := *r
+	result.Path = append([]string(nil), r.Path...)
+	result.History = make([]*StepAttempt, 0, len(r.History))
+	for _, attempt := range r.History {
+		item := *attempt
+		result.History = append(result.History, &item)
+	}
+	result.Context = append(json.RawMessage(nil), r.Context...)
+	if r.StepStatus != nil {
+		result.StepStatus = make(map[string]WorkStepStatus, len(r.StepStatus))
+		for k, v := range r.StepStatus {
+			result.StepStatus[k] = v
+		}
+	}
+	return &result
+}
+
+func (f *Workflow) newRunRecord(runID string, workflow string) *RunRecord {
+	record := &RunRecord{RunID: runID, Workflow: workflow, Status: WorkStepStatusPending}
+	if !f.IsDAG() && len(f.WorkSteps) > 0 {
+		record.CurrentStep = f.WorkSteps[0].GetName()
+	}
+	return record
+}
+
+// Run processes the workflow like Process and checkpoints the run to store before and after every step
+// and after every attempt: the step statuses, the history, the current step and the context, marshalled
+// to JSON. A run interrupted by a crash continues from its last checkpoint with Resume.
+// In DAG mode steps may run while the context is marshalled, so unless Concurrency is 1 the context must
+// be a sync.Locker: it is locked while marshalled, and the steps must hold the lock while accessing it.
+func (f *Workflow) Run(ctx context.Context, store CheckpointStore, runID string, context interface{}) (*Result, error) {
+	if runID == "" {
+		return nil, fmt.Errorf("workflow %s run needs an id", f.Name)
+	}
+	return f.run(ctx, &workflowRun{store: store, record: f.newRunRecord(runID, f.Name), data: context})
+}
+
+// Resume continues a run from its last checkpoint, its context is unmarshalled into context, which
+// must be a pointer. Completed steps are never run again. A step interrupted while running may have had
+// effects already, so it is only run again if it is an IdempotentStep, otherwise ErrStepInterrupted is
+// returned; its attempts recorded in the history count towards the MaxAttempts of its policy.
+// Resuming a completed run returns its result without running anything.
+func (f *Workflow) Resume(ctx context.Context, store CheckpointStore, runID string, context interface{}) (*Result, error) {
+	record, err := store.Load(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	return f.resume(ctx, store, record, context)
+}
+
+func (f *Workflow) resume(ctx context.Context, store CheckpointStore, record *RunRecord, data interface{}) (*Result, error) {
+	if record.Status == WorkStepStatusCompleted {
+		return &Result{RunID: record.RunID, Status: record.Status, Path: record.Path, StepStatus: record.StepStatus, History: record.History}, nil
+	}
+
+	attempts := make(map[string]int)
+	for _, step := range f.WorkSteps {
+		name := step.GetName()
+		if record.StepStatus[name] != WorkStepStatusProgressing {
+			continue
+		}
+		if !isIdempotent(step) {
+			return nil, fmt.Errorf("failed to resume run %s at step %s: %w", record.RunID, name, ErrStepInterrupted)
+		}
+		record.StepStatus[name] = WorkStepStatusPending
+		attempts[name] = getPreviousAttempts(record.History, name)
+	}
+
+	if len(record.Context) > 0 && data != nil {
+		if err := json.Unmarshal(record.Context, data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal context of run %s: %w", record.RunID, err)
+		}
+	}
+
+	return f.run(ctx, &workflowRun{store: store, record: record, data: data, attempts: attempts})
+}
+
+// checkpoint saves the state of the run, also when ctx is done so that cancelled runs can be resumed
+func (f *Workflow) checkpoint(ctx context.Context, r *workflowRun) error {
+	if r.store == nil {
+		return nil
+	}
+
+	r.record.Path = append([]string(nil), r.result.Path...)
+	r.record.History = append([]*StepAttempt(nil), r.result.History...)
+	r.record.StepStatus = make(map[string]WorkStepStatus, len(r.stepStatus))
+	for name, status := range r.stepStatus {
+		r.record.StepStatus[name] = status
+	}
+
+	if r.data != nil {
+		data, err := marshalContext(r.data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal context of run %s: %w", r.record.RunID, err)
+		}
+		r.record.Context = data
+	}
+
+	if err := r.store.Save(context.WithoutCancel(ctx), r.record); err != nil {
+		return fmt.Errorf("failed to checkpoint run %s: %w", r.record.RunID, err)
+	}
+	return nil
+}
+
+// marshalContext marshals the context of a run, holding its lock when it is a sync.Locker
+func marshalContext(data interface{}) ([]byte, error) {
+	if locker, ok := data.(sync.Locker); ok {
+		locker.Lock()
+		defer locker.Unlock()
+	}
+	return json.Marshal(data)
+}
+
+// MemoryCheckpointStore keeps run records in memory, for tests and single process deployments
+type MemoryCheckpointStore struct {
+	mu      sync.RWMutex
+	records map[string]*RunRecord
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{
+		records: make(map[string]*RunRecord),
+	}
+}
+
+func (s *MemoryCheckpointStore) Load(ctx context.Context, runID string) (*RunRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[runID]
+	if !ok {
+		return nil, ErrRunNotFound
+	}
+	return record.clone(), nil
+}
+
+func (s *MemoryCheckpointStore) Save(ctx context.Context, record *RunRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var version int64
+	if stored, ok := s.records[record.RunID]; ok {
+		version = stored.Version
+	}
+	if version != record.Version {
+		return ErrRunVersionConflict
+	}
+
+	record.Version++
+	record.UpdatedAt = time.Now()
+	s.records[record.RunID] = record.clone()
+	return nil
+}
+
+func (s *MemoryCheckpointStore) Delete(ctx context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, runID)
+	return nil
+}
+
+func (s *MemoryCheckpointStore) List(ctx context.Context, status string) ([]*RunRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*RunRecord, 0, len(s.records))
+	for _, record := range s.records {
+		if status == "" || record.Status == status {
+			result = append(result, record.clone())
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].RunID < result[j].RunID })
+	return result, nil
+}
@@ -0,0 +1,245 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// testStep runs process, counting its calls
+type testStep struct {
+	name       string
+	idempotent bool
+	process    func(ctx context.Context, data *testContext) (string, error)
+	calls      int
+}
+
+func (s *testStep) GetName() string {
+	return s.name
+}
+
+func (s *testStep) IsIdempotent() bool {
+	return s.idempotent
+}
+
+func (s *testStep) Process(ctx context.Context, context interface{}) (string, error) {
+	s.calls++
+	if s.process == nil {
+		return WorkStepNext, nil
+	}
+	return s.process(ctx, context.(*testContext))
+}
+
+// testContext is the context of the test runs, steps record their name in Done
+type testContext struct {
+	sync.Mutex
+	Done []string `json:"done"`
+}
+
+func (c *testContext) add(name string) {
+	c.Lock()
+	defer c.Unlock()
+	c.Done = append(c.Done, name)
+}
+
+func recordStep(name string) func(ctx context.Context, data *testContext) (string, error) {
+	return func(ctx context.Context, data *testContext) (string, error) {
+		data.add(name)
+		return WorkStepNext, nil
+	}
+}
+
+// crashStore stops saving once crashed, as if the process had died
+type crashStore struct {
+	*MemoryCheckpointStore
+	mu      sync.Mutex
+	crashed bool
+	onSave  func(record *RunRecord)
+}
+
+var errCrashed = errors.New("crashed")
+
+func newCrashStore() *crashStore {
+	return &crashStore{MemoryCheckpointStore: NewMemoryCheckpointStore()}
+}
+
+func (s *crashStore) crash() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crashed = true
+}
+
+func (s *crashStore) Save(ctx context.Context, record *RunRecord) error {
+	s.mu.Lock()
+	crashed := s.crashed
+	s.mu.Unlock()
+	if crashed {
+		return errCrashed
+	}
+
+	if err := s.MemoryCheckpointStore.Save(ctx, record); err != nil {
+		return err
+	}
+	if s.onSave != nil {
+		s.onSave(record)
+	}
+	return nil
+}
+
+func TestRunCheckpointsCompletedRun(t *testing.T) {
+	steps := []*testStep{{name: "a"}, {name: "b"}}
+	workflow := NewWorkflow("w")
+	for _, step := range steps {
+		step.process = recordStep(step.name)
+		workflow.AddWorkStep(step)
+	}
+	store := NewMemoryCheckpointStore()
+
+	result, err := workflow.Run(context.Background(), store, "r1", &testContext{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Status != WorkStepStatusCompleted || len(result.Path) != 2 {
+		t.Errorf("Run result: status %s, path %v", result.Status, result.Path)
+	}
+
+	record, err := store.Load(context.Background(), "r1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if record.Status != WorkStepStatusCompleted || record.CurrentStep != "" || string(record.Context) != `{"done":["a","b"]}` {
+		t.Errorf("record: status %s, current step %q, context %s", record.Status, record.CurrentStep, record.Context)
+	}
+	if len(record.History) != 2 || record.StepStatus["b"] != WorkStepStatusCompleted {
+		t.Errorf("record: history %d attempts, step status %v", len(record.History), record.StepStatus)
+	}
+
+	// resuming a completed run runs nothing
+	result, err = workflow.Resume(context.Background(), store, "r1", &testContext{})
+	if err != nil || result.Status != WorkStepStatusCompleted {
+		t.Errorf("Resume: status %s, error %v", result.Status, err)
+	}
+	for _, step := range steps {
+		if step.calls != 1 {
+			t.Errorf("step %s ran %d times, want 1", step.name, step.calls)
+		}
+	}
+}
+
+func TestResumeAfterCrash(t *testing.T) {
+	for _, idempotent := range []bool{true, false} {
+		store := newCrashStore()
+		a := &testStep{name: "a", process: recordStep("a")}
+		b := &testStep{name: "b", idempotent: idempotent}
+		b.process = func(ctx context.Context, data *testContext) (string, error) {
+			if b.calls == 1 {
+				store.crash()
+			}
+			data.add("b")
+			return WorkStepNext, nil
+		}
+		c := &testStep{name: "c", process: recordStep("c")}
+		workflow := NewWorkflow("w").AddWorkStep(a).AddWorkStep(b).AddWorkStep(c)
+		management := NewManagement().RegisterWorkflow("w", workflow).SetCheckpointStore(store)
+
+		if _, err := management.StartRun(context.Background(), "w", "r1", &testContext{}); !errors.Is(err, errCrashed) {
+			t.Fatalf("StartRun error = %v, want %v", err, errCrashed)
+		}
+
+		incomplete, err := management.ListIncompleteRuns(context.Background())
+		if err != nil || len(incomplete) != 1 {
+			t.Fatalf("ListIncompleteRuns = %v, %v, want the crashed run", incomplete, err)
+		}
+		if record := incomplete[0]; record.CurrentStep != "b" || record.StepStatus["b"] != WorkStepStatusProgressing {
+			t.Errorf("crashed run: current step %q, step status %v", record.CurrentStep, record.StepStatus)
+		}
+
+		store.mu.Lock()
+		store.crashed = false
+		store.mu.Unlock()
+
+		data := &testContext{}
+		result, err := management.ResumeRun(context.Background(), "r1", data)
+		if !idempotent {
+			if !errors.Is(err, ErrStepInterrupted) {
+				t.Errorf("ResumeRun error = %v, want %v", err, ErrStepInterrupted)
+			}
+			continue
+		}
+
+		if err != nil || result.Status != WorkStepStatusCompleted {
+			t.Fatalf("ResumeRun: status %v, error %v", result, err)
+		}
+		if a.calls != 1 || b.calls != 2 || c.calls != 1 {
+			t.Errorf("steps ran a %d, b %d, c %d times, want 1, 2, 1", a.calls, b.calls, c.calls)
+		}
+		if len(data.Done) != 3 || data.Done[0] != "a" {
+			t.Errorf("resumed context = %v, want a restored and b, c added", data.Done)
+		}
+		if incomplete, _ := management.ListIncompleteRuns(context.Background()); len(incomplete) != 0 {
+			t.Errorf("ListIncompleteRuns = %d runs after the resume, want none", len(incomplete))
+		}
+	}
+}
+
+func TestResumeDAGAfterCrash(t *testing.T) {
+	store := newCrashStore()
+	chargeSaved := make(chan struct{})
+	var once sync.Once
+	store.onSave = func(record *RunRecord) {
+		if record.StepStatus["charge"] == WorkStepStatusCompleted {
+			once.Do(func() { close(chargeSaved) })
+		}
+	}
+
+	charge := &testStep{name: "charge", process: recordStep("charge")}
+	slow := &testStep{name: "slow", idempotent: true}
+	slow.process = func(ctx context.Context, data *testContext) (string, error) {
+		if slow.calls == 1 {
+			<-chargeSaved
+			store.crash()
+		}
+		data.add("slow")
+		return WorkStepNext, nil
+	}
+	workflow := NewWorkflow("w").AddWorkStep(charge)
+	workflow.AddDependentStep(slow, JoinAll)
+
+	if _, err := workflow.Run(context.Background(), store, "r1", &testContext{}); !errors.Is(err, errCrashed) {
+		t.Fatalf("Run error = %v, want %v", err, errCrashed)
+	}
+
+	record, err := store.Load(context.Background(), "r1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if record.StepStatus["charge"] != WorkStepStatusCompleted || record.CurrentStep != "slow" || string(record.Context) != `{"done":["charge"]}` {
+		t.Errorf("crashed run: step status %v, current step %q, context %s", record.StepStatus, record.CurrentStep, record.Context)
+	}
+
+	store.mu.Lock()
+	store.crashed = false
+	store.mu.Unlock()
+
+	result, err := workflow.Resume(context.Background(), store, "r1", &testContext{})
+	if err != nil || result.Status != WorkStepStatusCompleted {
+		t.Fatalf("Resume: %v, %v", result, err)
+	}
+	if charge.calls != 1 || slow.calls != 2 {
+		t.Errorf("charge ran %d times and slow %d times, want 1 and 2", charge.calls, slow.calls)
+	}
+}
+
+func TestRunDAGNeedsLockedContext(t *testing.T) {
+	workflow := NewWorkflow("w").AddWorkStep(&testStep{name: "a"}).AddDependentStep(&testStep{name: "b"}, JoinAll)
+
+	if _, err := workflow.Run(context.Background(), NewMemoryCheckpointStore(), "r1", map[string]int{}); err == nil {
+		t.Error("Run of a parallel DAG with an unlocked context succeeded, want an error")
+	}
+
+	workflow.Concurrency = 1
+	if _, err := workflow.Run(context.Background(), NewMemoryCheckpointStore(), "r2", map[string]int{}); err != nil {
+		t.Errorf("Run with Concurrency 1: %v", err)
+	}
+}
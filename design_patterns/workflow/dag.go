@@ -0,0 +1,298 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Algo2147483647/golang_toolkit/math/graph"
+)
+
+// Joins of a step depending on several steps
+const (
+	JoinAll = "all" // the step runs once all the steps it depends on completed
+	JoinAny = "any" // the step runs once any step it depends on completed
+)
+
+// Dependency declares the steps a step of a DAG workflow depends on
+type Dependency struct {
+	DependsOn []string
+	Join      string // JoinAll (default) or JoinAny
+}
+
+// AddDependentStep appends a step depending on the named steps, which switches the workflow to DAG mode
+func (f *Workflow) AddDependentStep(step WorkStep, join string, dependsOn ...string) *Workflow {
+	if f.Dependencies == nil {
+		f.Dependencies = make(map[string]*Dependency)
+	}
+	f.Dependencies[step.GetName()] = &Dependency{DependsOn: dependsOn, Join: join}
+	return f.AddWorkStep(step)
+}
+
+// IsDAG reports whether the workflow runs in DAG mode, i.e. some step was declared with dependencies
+func (f *Workflow) IsDAG() bool {
+	return f.Dependencies != nil
+}
+
+// dagStep is a step in the dependency graph of a workflow
+type dagStep struct {
+	step WorkStep
+	join string
+	pre  []graph.Node
+	post []graph.Node
+}
+
+func (s *dagStep) GetPreNodes() []graph.Node {
+	return s.pre
+}
+
+func (s *dagStep) GetPostNodes() []graph.Node {
+	return s.post
+}
+
+type dagEdge struct {
+	from, to graph.Node
+}
+
+func (e *dagEdge) GetNodes() (graph.Node, graph.Node) {
+	return e.from, e.to
+}
+
+type dagGraph struct {
+	steps []*dagStep
+}
+
+func (g *dagGraph) GetNodes() []graph.Node {
+	result := make([]graph.Node, 0, len(g.steps))
+	for _, step := range g.steps {
+		result = append(result, step)
+	}
+	return result
+}
+
+func (g *dagGraph) GetEdges() []graph.Edge {
+	result := make([]graph.Edge, 0)
+	for _, step := range g.steps {
+		for _, post := range step.post {
+			result = append(result, &dagEdge{from: step, to: post})
+		}
+	}
+	return result
+}
+
+// Validate checks the dependency graph of a DAG workflow: step names must be distinct, dependencies must
+// name steps of the workflow, joins must be known and the graph must have no cycle
+func (f *Workflow) Validate() error {
+	_, err := f.getDAGOrder()
+	return err
+}
+
+// getDAGOrder builds the dependency graph and returns its steps in topological order
+func (f *Workflow) getDAGOrder() ([]*dagStep, error) {
+	g := &dagGraph{steps: make([]*dagStep, 0, len(f.WorkSteps))}
+	steps := make(map[string]*dagStep, len(f.WorkSteps))
+	for _, step := range f.WorkSteps {
+		name := step.GetName()
+		if _, ok := steps[name]; ok {
+			return nil, fmt.Errorf("workflow %s has duplicate step %s", f.Name, name)
+		}
+		steps[name] = &dagStep{step: step, join: JoinAll}
+		g.steps = append(g.steps, steps[name])
+	}
+
+	for name := range f.Dependencies {
+		if _, ok := steps[name]; !ok {
+			return nil, fmt.Errorf("workflow %s has dependencies for unknown step %s", f.Name, name)
+		}
+	}
+
+	for _, step := range g.steps {
+		name := step.step.GetName()
+		dependency, ok := f.Dependencies[name]
+		if !ok {
+			continue
+		}
+
+		switch dependency.Join {
+		case "", JoinAll:
+		case JoinAny:
+			step.join = JoinAny
+		default:
+			return nil, fmt.Errorf("step %s of workflow %s has unknown join %s", name, f.Name, dependency.Join)
+		}
+
+		for _, preName := range dependency.DependsOn {
+			pre, ok := steps[preName]
+			if !ok {
+				return nil, fmt.Errorf("step %s of workflow %s depends on unknown step %s", name, f.Name, preName)
+			}
+			step.pre = append(step.pre, pre)
+			pre.post = append(pre.post, step)
+		}
+	}
+
+	if graph.HasCycle(g) {
+		return nil, fmt.Errorf("steps of workflow %s have a dependency cycle", f.Name)
+	}
+
+	nodes := graph.TopologicalSort(g)
+	result := make([]*dagStep, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, node.(*dagStep))
+	}
+	return result, nil
+}
+
+// stepOutcome is sent back by a step for every attempt, and once it has run
+type stepOutcome struct {
+	step    *dagStep
+	attempt *StepAttempt  // set for an attempt, nil once the step has run
+	saved   chan struct{} // closed once the attempt is checkpointed
+	err     error
+}
+
+// processDAG runs the steps once their join is satisfied, independent steps run in parallel on at most
+// f.Concurrency goroutines. Ready steps are started in topological order, steps already completed by the
+// run are not started again. The first failing step cancels the context given to the running steps, and
+// processDAG returns once they are done.
+func (f *Workflow) processDAG(ctx context.Context, r *workflowRun) error {
+	order, err := f.getDAGOrder()
+	if err != nil {
+		return err
+	}
+
+	limit := f.Concurrency
+	if limit <= 0 {
+		limit = len(order)
+	}
+	if _, ok := r.data.(sync.Locker); r.store != nil && r.data != nil && limit > 1 && !ok {
+		return fmt.Errorf("workflow %s runs steps in parallel, its context must be a sync.Locker to be checkpointed", f.Name)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan stepOutcome)
+	started := make(map[*dagStep]bool, len(order))
+	completed := make(map[*dagStep]bool, len(order))
+	running := 0
+	var firstErr error
+
+	for _, step := range order {
+		if r.stepStatus[step.step.GetName()] == WorkStepStatusCompleted {
+			started[step] = true
+			completed[step] = true
+		}
+	}
+
+	checkpoint := func() error {
+		names := make([]string, 0)
+		for _, step := range order {
+			if name := step.step.GetName(); r.stepStatus[name] == WorkStepStatusProgressing {
+				names = append(names, name)
+			}
+		}
+		r.record.CurrentStep = strings.Join(names, ",")
+		return f.checkpoint(ctx, r)
+	}
+
+	isReady := func(step *dagStep) bool {
+		if started[step] {
+			return false
+		}
+		count := 0
+		for _, pre := range step.pre {
+			if completed[pre.(*dagStep)] {
+				count++
+			}
+		}
+		if step.join == JoinAny {
+			return len(step.pre) == 0 || count > 0
+		}
+		return count == len(step.pre)
+	}
+
+	for len(completed) < len(order) || running > 0 {
+		// 1. Start the ready steps while below the concurrency limit
+		if firstErr == nil && ctx.Err() == nil {
+			for _, step := range order {
+				if running >= limit {
+					break
+				}
+				if !isReady(step) {
+					continue
+				}
+
+				name := step.step.GetName()
+				started[step] = true
+				r.stepStatus[name] = WorkStepStatusProgressing
+				if err := checkpoint(); err != nil {
+					firstErr = err
+					cancel()
+					break
+				}
+
+				running++
+				previous := r.attempts[name]
+				delete(r.attempts, name)
+				go func(step *dagStep) {
+					_, err := f.executeStep(runCtx, step.step, r.data, previous, func(attempt *StepAttempt) error {
+						saved := make(chan struct{})
+						done <- stepOutcome{step: step, attempt: attempt, saved: saved}
+						<-saved
+						return nil
+					})
+					done <- stepOutcome{step: step, err: err}
+				}(step)
+			}
+		}
+
+		if running == 0 {
+			break
+		}
+
+		// 2. Wait for an attempt or a step to finish
+		outcome := <-done
+		if outcome.attempt != nil {
+			r.result.History = append(r.result.History, outcome.attempt)
+			if err := checkpoint(); err != nil && firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+			close(outcome.saved)
+			continue
+		}
+		running--
+
+		name := outcome.step.step.GetName()
+		r.result.Path = append(r.result.Path, name)
+		if outcome.err != nil {
+			r.stepStatus[name] = WorkStepStatusFailed
+			if firstErr == nil {
+				firstErr = fmt.Errorf("workflow %s failed at step %s: %w", f.Name, name, outcome.err)
+				cancel()
+			}
+			continue
+		}
+		r.stepStatus[name] = WorkStepStatusCompleted
+		completed[outcome.step] = true
+
+		if err := checkpoint(); err != nil && firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	r.record.CurrentStep = ""
+	if firstErr != nil {
+		return firstErr
+	}
+	if len(completed) < len(order) {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("workflow %s is cancelled: %w", f.Name, err)
+		}
+		return fmt.Errorf("workflow %s stopped with %d steps not run", f.Name, len(order)-len(completed))
+	}
+	return nil
+}
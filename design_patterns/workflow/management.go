@@ -1,7 +1,13 @@
 package workflow
 
+import (
+	"context"
+	"fmt"
+)
+
 type Management struct {
 	workflows map[string]*Workflow
+	store     CheckpointStore
 }
 
 func NewManagement() *Management {
@@ -31,3 +37,72 @@ func (m *Management) DeleteWorkflow(key string) *Management {
 	delete(m.workflows, key)
 	return m
 }
+
+// SetCheckpointStore sets the store of the runs started with StartRun
+func (m *Management) SetCheckpointStore(store CheckpointStore) *Management {
+	m.store = store
+	return m
+}
+
+// StartRun runs the workflow registered under key with checkpoints, see Workflow.Run
+func (m *Management) StartRun(ctx context.Context, key string, runID string, context interface{}) (*Result, error) {
+	workflow, err := m.getRunWorkflow(key)
+	if err != nil {
+		return nil, err
+	}
+	if runID == "" {
+		return nil, fmt.Errorf("workflow %s run needs an id", key)
+	}
+
+	return workflow.run(ctx, &workflowRun{store: m.store, record: workflow.newRunRecord(runID, key), data: context})
+}
+
+// ListIncompleteRuns returns the runs that did not complete: interrupted by a crash or cancellation, or failed
+func (m *Management) ListIncompleteRuns(ctx context.Context) ([]*RunRecord, error) {
+	if m.store == nil {
+		return nil, fmt.Errorf("workflow management has no checkpoint store")
+	}
+
+	records, err := m.store.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*RunRecord, 0, len(records))
+	for _, record := range records {
+		if record.Status != WorkStepStatusCompleted {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+// ResumeRun continues a run with the workflow it was started with, see Workflow.Resume
+func (m *Management) ResumeRun(ctx context.Context, runID string, context interface{}) (*Result, error) {
+	if m.store == nil {
+		return nil, fmt.Errorf("workflow management has no checkpoint store")
+	}
+
+	record, err := m.store.Load(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	workflow, err := m.getRunWorkflow(record.Workflow)
+	if err != nil {
+		return nil, err
+	}
+	return workflow.resume(ctx, m.store, record, context)
+}
+
+func (m *Management) getRunWorkflow(key string) (*Workflow, error) {
+	if m.store == nil {
+		return nil, fmt.Errorf("workflow management has no checkpoint store")
+	}
+
+	workflow := m.GetWorkflow(key)
+	if workflow == nil {
+		return nil, fmt.Errorf("workflow %s is not registered", key)
+	}
+	return workflow, nil
+}
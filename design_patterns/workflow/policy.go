@@ -0,0 +1,227 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// StepPolicy retries a failing step with an exponential backoff and bounds the duration of each attempt.
+// In JSON the durations are time.ParseDuration strings, e.g. 500ms.
+type StepPolicy struct {
+	MaxAttempts    int           `json:"max_attempts"` // including the first one, 0 or 1 means no retry
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff"` // 0 means unbounded
+	Multiplier     float64       `json:"multiplier"`  // 0 means 2
+	Jitter         float64       `json:"jitter"`      // fraction of the backoff that is randomized, from 0 to 1
+	Timeout        time.Duration `json:"timeout"`     // of each attempt, through its context, 0 means none
+
+	// Retryable tells whether an attempt failing with err is retried, nil retries every error
+	Retryable func(err error) bool `json:"-"`
+}
+
+// PolicyStep is implemented by steps with their own policy, Workflow.SetStepPolicy takes precedence
+type PolicyStep interface {
+	GetStepPolicy() *StepPolicy
+}
+
+// StepAttempt records an attempt of a step in the run history
+type StepAttempt struct {
+	Step       string    `json:"step"`
+	Attempt    int       `json:"attempt"` // from 1
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Backoff returns the delay before the given retry, starting from 1. With Jitter j the delay is drawn
+// uniformly between (1-j) and 1 times the exponential backoff, so that failing runs do not retry in step.
+func (p *StepPolicy) Backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		backoff *= multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			backoff = float64(p.MaxBackoff)
+			break
+		}
+		if backoff >= math.MaxInt64 {
+			break
+		}
+	}
+
+	jitter := min(max(p.Jitter, 0), 1)
+	backoff *= 1 - jitter*rand.Float64()
+	if backoff >= math.MaxInt64 {
+		// beyond time.Duration, whose conversion from such a float is undefined
+		return math.MaxInt64
+	}
+	return time.Duration(backoff)
+}
+
+// stepPolicyJSON is the JSON form of a StepPolicy
+type stepPolicyJSON struct {
+	MaxAttempts    int     `json:"max_attempts"`
+	InitialBackoff string  `json:"initial_backoff,omitempty"`
+	MaxBackoff     string  `json:"max_backoff,omitempty"`
+	Multiplier     float64 `json:"multiplier"`
+	Jitter         float64 `json:"jitter"`
+	Timeout        string  `json:"timeout,omitempty"`
+}
+
+func (p *StepPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&stepPolicyJSON{
+		MaxAttempts:    p.MaxAttempts,
+		InitialBackoff: formatDuration(p.InitialBackoff),
+		MaxBackoff:     formatDuration(p.MaxBackoff),
+		Multiplier:     p.Multiplier,
+		Jitter:         p.Jitter,
+		Timeout:        formatDuration(p.Timeout),
+	})
+}
+
+func (p *StepPolicy) UnmarshalJSON(data []byte) error {
+	var value stepPolicyJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	p.MaxAttempts, p.Multiplier, p.Jitter = value.MaxAttempts, value.Multiplier, value.Jitter
+	for _, field := range []struct {
+		name  string
+		text  string
+		value *time.Duration
+	}{
+		{"initial_backoff", value.InitialBackoff, &p.InitialBackoff},
+		{"max_backoff", value.MaxBackoff, &p.MaxBackoff},
+		{"timeout", value.Timeout, &p.Timeout},
+	} {
+		*field.value = 0
+		if field.text == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(field.text)
+		if err != nil {
+			return fmt.Errorf("invalid %s of step policy: %w", field.name, err)
+		}
+		*field.value = duration
+	}
+	return nil
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+func (p *StepPolicy) isRetryable(err error) bool {
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// SetStepPolicy sets the policy of the named step
+func (f *Workflow) SetStepPolicy(name string, policy *StepPolicy) *Workflow {
+	if f.Policies == nil {
+		f.Policies = make(map[string]*StepPolicy)
+	}
+	f.Policies[name] = policy
+	return f
+}
+
+func (f *Workflow) getStepPolicy(step WorkStep) *StepPolicy {
+	if policy, ok := f.Policies[step.GetName()]; ok {
+		return policy
+	}
+	if policyStep, ok := step.(PolicyStep); ok {
+		return policyStep.GetStepPolicy()
+	}
+	return nil
+}
+
+// executeStep processes the step under its policy: an attempt failing with a retryable error is retried
+// after the backoff until MaxAttempts is reached. The run stops retrying once ctx is done. Every attempt
+// is passed to onAttempt as it finishes, an error of onAttempt stops the step. previous is the number of
+// attempts made before the run was resumed, they count towards MaxAttempts.
+func (f *Workflow) executeStep(ctx context.Context, step WorkStep, data interface{}, previous int, onAttempt func(*StepAttempt) error) (string, error) {
+	policy := f.getStepPolicy(step)
+	maxAttempts := 1
+	if policy != nil && policy.MaxAttempts > 1 {
+		maxAttempts = policy.MaxAttempts
+	}
+	if previous >= maxAttempts {
+		return "", fmt.Errorf("failed after %d attempts before the run was resumed", previous)
+	}
+
+	for attempt := previous + 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if policy != nil && policy.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		}
+
+		record := &StepAttempt{Step: step.GetName(), Attempt: attempt, StartedAt: time.Now()}
+		next, err := step.Process(attemptCtx, data)
+		cancel()
+		record.FinishedAt = time.Now()
+		if err != nil {
+			record.Error = err.Error()
+		}
+
+		if attemptErr := onAttempt(record); attemptErr != nil {
+			return "", attemptErr
+		}
+		if err == nil {
+			return next, nil
+		}
+
+		if attempt >= maxAttempts || ctx.Err() != nil || !policy.isRetryable(err) {
+			if attempt > 1 {
+				err = fmt.Errorf("failed after %d attempts: %w", attempt, err)
+			}
+			return "", err
+		}
+
+		if sleepErr := sleep(ctx, policy.Backoff(attempt)); sleepErr != nil {
+			return "", fmt.Errorf("%w (last attempt: %v)", sleepErr, err)
+		}
+	}
+}
+
+// getPreviousAttempts returns the number of attempts of the step interrupted while running: the attempt
+// of its last record in history if that one failed, 0 if it succeeded in an earlier visit of the step
+func getPreviousAttempts(history []*StepAttempt, name string) int {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Step != name {
+			continue
+		}
+		if history[i].Error == "" {
+			return 0
+		}
+		return history[i].Attempt
+	}
+	return 0
+}
+
+// sleep waits for d, or returns the error of ctx if it is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,192 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errFlaky = errors.New("flaky")
+
+// failingStep fails its first failures calls with err, 0 failures means it always fails
+func failingStep(name string, failures int, err error) *testStep {
+	step := &testStep{name: name, idempotent: true}
+	step.process = func(ctx context.Context, data *testContext) (string, error) {
+		if failures == 0 || step.calls <= failures {
+			return "", err
+		}
+		return WorkStepNext, nil
+	}
+	return step
+}
+
+func TestStepPolicyRetry(t *testing.T) {
+	step := failingStep("a", 2, errFlaky)
+	workflow := NewWorkflow("w").AddWorkStep(step).SetStepPolicy("a", &StepPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	store := NewMemoryCheckpointStore()
+
+	result, err := workflow.Run(context.Background(), store, "r1", &testContext{})
+	if err != nil || result.Status != WorkStepStatusCompleted {
+		t.Fatalf("Run: %v, %v", result, err)
+	}
+	if step.calls != 3 {
+		t.Errorf("step ran %d times, want 3", step.calls)
+	}
+
+	record, err := store.Load(context.Background(), "r1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(record.History) != 3 || record.History[0].Error == "" || record.History[2].Error != "" {
+		t.Errorf("history has %d attempts, want 2 failed and 1 succeeded", len(record.History))
+	}
+}
+
+func TestStepPolicyFailure(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	tests := []struct {
+		name      string
+		step      *testStep
+		policy    *StepPolicy
+		calls     int
+		wantError error
+	}{
+		{
+			name:      "exhausted",
+			step:      failingStep("a", 0, errFlaky),
+			policy:    &StepPolicy{MaxAttempts: 3},
+			calls:     3,
+			wantError: errFlaky,
+		},
+		{
+			name:      "not retryable",
+			step:      failingStep("a", 0, errPermanent),
+			policy:    &StepPolicy{MaxAttempts: 3, Retryable: func(err error) bool { return !errors.Is(err, errPermanent) }},
+			calls:     1,
+			wantError: errPermanent,
+		},
+		{
+			name: "attempt timeout",
+			step: &testStep{name: "a", process: func(ctx context.Context, data *testContext) (string, error) {
+				<-ctx.Done()
+				return "", ctx.Err()
+			}},
+			policy:    &StepPolicy{MaxAttempts: 2, Timeout: 10 * time.Millisecond},
+			calls:     2,
+			wantError: context.DeadlineExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		workflow := NewWorkflow("w").AddWorkStep(test.step).SetStepPolicy("a", test.policy)
+		store := NewMemoryCheckpointStore()
+
+		result, err := workflow.Run(context.Background(), store, "r1", &testContext{})
+		if !errors.Is(err, test.wantError) {
+			t.Errorf("%s: Run error = %v, want %v", test.name, err, test.wantError)
+		}
+		if test.step.calls != test.calls {
+			t.Errorf("%s: step ran %d times, want %d", test.name, test.step.calls, test.calls)
+		}
+		if result == nil || result.Status != WorkStepStatusFailed || result.StepStatus["a"] != WorkStepStatusFailed {
+			t.Errorf("%s: Run result = %+v, want it failed", test.name, result)
+		}
+		if record, err := store.Load(context.Background(), "r1"); err != nil || record.Status != WorkStepStatusFailed {
+			t.Errorf("%s: checkpoint = %+v, %v, want it failed", test.name, record, err)
+		}
+	}
+}
+
+func TestStepPolicyCountsAttemptsBeforeResume(t *testing.T) {
+	store := newCrashStore()
+	step := &testStep{name: "a", idempotent: true}
+	crashed := false
+	step.process = func(ctx context.Context, data *testContext) (string, error) {
+		if step.calls == 2 && !crashed {
+			crashed = true
+			store.crash()
+		}
+		return "", errFlaky
+	}
+	workflow := NewWorkflow("w").AddWorkStep(step).SetStepPolicy("a", &StepPolicy{MaxAttempts: 3})
+
+	if _, err := workflow.Run(context.Background(), store, "r1", &testContext{}); !errors.Is(err, errCrashed) {
+		t.Fatalf("Run error = %v, want %v", err, errCrashed)
+	}
+
+	store.mu.Lock()
+	store.crashed = false
+	store.mu.Unlock()
+
+	// the attempt that crashed was not recorded, so one attempt is left out of three
+	step.calls = 0
+	if _, err := workflow.Resume(context.Background(), store, "r1", &testContext{}); !errors.Is(err, errFlaky) || !strings.Contains(err.Error(), "3 attempts") {
+		t.Errorf("Resume error = %v, want the step failed after 3 attempts", err)
+	}
+	if step.calls != 2 {
+		t.Errorf("step ran %d times on resume, want 2", step.calls)
+	}
+
+	record, err := store.Load(context.Background(), "r1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if last := record.History[len(record.History)-1]; last.Attempt != 3 {
+		t.Errorf("last attempt is %d, want 3", last.Attempt)
+	}
+}
+
+func TestStepPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		policy *StepPolicy
+		retry  int
+		want   time.Duration
+	}{
+		{&StepPolicy{InitialBackoff: time.Second}, 1, time.Second},
+		{&StepPolicy{InitialBackoff: time.Second}, 3, 4 * time.Second},
+		{&StepPolicy{InitialBackoff: time.Second, Multiplier: 3}, 3, 9 * time.Second},
+		{&StepPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 10, 5 * time.Second},
+		{&StepPolicy{InitialBackoff: time.Second}, 100, math.MaxInt64},
+	}
+
+	for _, test := range tests {
+		if got := test.policy.Backoff(test.retry); got != test.want {
+			t.Errorf("Backoff(%d) of %+v = %v, want %v", test.retry, test.policy, got, test.want)
+		}
+	}
+
+	policy := &StepPolicy{InitialBackoff: time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if got := policy.Backoff(1); got < 500*time.Millisecond || got > time.Second {
+			t.Fatalf("Backoff with jitter = %v, want between 500ms and 1s", got)
+		}
+	}
+}
+
+func TestStepPolicyJSON(t *testing.T) {
+	policy := &StepPolicy{MaxAttempts: 3, InitialBackoff: 500 * time.Millisecond, Timeout: time.Minute}
+
+	data, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if want := `{"max_attempts":3,"initial_backoff":"500ms","multiplier":0,"jitter":0,"timeout":"1m0s"}`; string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+
+	var decoded StepPolicy
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if decoded.MaxAttempts != 3 || decoded.InitialBackoff != policy.InitialBackoff || decoded.Timeout != policy.Timeout {
+		t.Errorf("Unmarshal = %+v, want %+v", decoded, policy)
+	}
+
+	if err := json.Unmarshal([]byte(`{"timeout":"soon"}`), &decoded); err == nil {
+		t.Error("Unmarshal of an invalid timeout succeeded, want an error")
+	}
+}
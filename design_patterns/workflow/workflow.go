@@ -2,12 +2,16 @@ package workflow
 
 import (
 	"context"
+	"fmt"
 )
 
 type Workflow struct {
-	Name      string
-	Status    string
-	WorkSteps []WorkStep
+	Name         string
+	Status       string
+	WorkSteps    []WorkStep
+	Dependencies map[string]*Dependency // dependencies by step name, set in DAG mode
+	Concurrency  int                    // maximum of steps running at once in DAG mode, 0 for no limit
+	Policies     map[string]*StepPolicy // retry and timeout policies by step name
 }
 
 type WorkStepStatus string
@@ -19,6 +23,22 @@ const (
 	WorkStepStatusFailed      = "Failed"
 )
 
+// Next step names returned by WorkStep.Process besides the names of the steps
+const (
+	WorkStepNext = ""      // the step following in WorkSteps, the workflow ends after the last one
+	WorkStepEnd  = "<end>" // the workflow ends
+)
+
+// Result records a Process of a workflow
+type Result struct {
+	RunID      string                    // set for checkpointed runs
+	Status     string                    // WorkStepStatusCompleted or WorkStepStatusFailed
+	Path       []string                  // names of the steps processed, in the order they finished
+	StepStatus map[string]WorkStepStatus // status of every step
+	History    []*StepAttempt            // every attempt of the steps, in the order they finished
+	Error      error
+}
+
 func NewWorkflow(name string) *Workflow {
 	return &Workflow{
 		Name:   name,
@@ -26,11 +46,144 @@ func NewWorkflow(name string) *Workflow {
 	}
 }
 
-func (f *Workflow) Process(ctx context.Context, context interface{}) error {
-	stepIndex := 0
+// AddWorkStep appends a step, the first step added is where Process starts
+func (f *Workflow) AddWorkStep(step WorkStep) *Workflow {
+	f.WorkSteps = append(f.WorkSteps, step)
+	return f
+}
+
+// GetWorkStepIndex returns the index of the step with the name in WorkSteps, or -1
+func (f *Workflow) GetWorkStepIndex(name string) int {
+	for i, step := range f.WorkSteps {
+		if step.GetName() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Process runs the steps from the first one. Each step names the step to run after it: another step,
+// WorkStepNext or WorkStepEnd. Steps may be visited again, e.g. to loop until a condition holds.
+// In DAG mode (see AddDependentStep) the steps run along their dependencies instead, and the names
+// they return are ignored.
+// A step with a StepPolicy is retried before it is considered failed, see SetStepPolicy.
+// Process stops at the first step failing, at an unknown step name, or when ctx is done; the error is
+// returned and also recorded in the result. See Run for runs surviving a restart.
+// Process does not modify the workflow, so it can be processed by several goroutines at once.
+func (f *Workflow) Process(ctx context.Context, context interface{}) (*Result, error) {
+	return f.run(ctx, &workflowRun{record: f.newRunRecord("", f.Name), data: context})
+}
+
+// workflowRun is the state of a run shared by both modes, it is checkpointed to store when set
+type workflowRun struct {
+	store      CheckpointStore
+	record     *RunRecord
+	data       interface{}
+	result     *Result
+	stepStatus map[string]WorkStepStatus
+	attempts   map[string]int // attempts made by the steps interrupted before the run was resumed
+}
+
+// run processes the workflow from the state of the run record, a new record starts from the first step
+func (f *Workflow) run(ctx context.Context, r *workflowRun) (*Result, error) {
+	r.record.Status = WorkStepStatusProgressing
+	r.stepStatus = make(map[string]WorkStepStatus, len(f.WorkSteps))
+	for _, step := range f.WorkSteps {
+		r.stepStatus[step.GetName()] = WorkStepStatusPending
+	}
+	for name, status := range r.record.StepStatus {
+		r.stepStatus[name] = status
+	}
+
+	r.result = &Result{
+		RunID:   r.record.RunID,
+		Path:    append(make([]string, 0), r.record.Path...),
+		History: append(make([]*StepAttempt, 0), r.record.History...),
+	}
+
+	err := f.checkpoint(ctx, r)
+	if err == nil {
+		if f.IsDAG() {
+			err = f.processDAG(ctx, r)
+		} else {
+			err = f.process(ctx, r)
+		}
+	}
+
+	r.record.Status = WorkStepStatusCompleted
+	r.record.Error = ""
+	if err != nil {
+		r.record.Status = WorkStepStatusFailed
+		r.record.Error = err.Error()
+	}
+	if checkpointErr := f.checkpoint(ctx, r); checkpointErr != nil && err == nil {
+		r.record.Status = WorkStepStatusFailed
+		err = checkpointErr
+	}
+
+	result := r.result
+	result.Status = r.record.Status
+	result.StepStatus = r.stepStatus
+	result.Error = err
+	return result, err
+}
+
+// process runs the steps one after the other from the current step of the run record
+func (f *Workflow) process(ctx context.Context, r *workflowRun) error {
+	if r.record.CurrentStep == "" {
+		return nil
+	}
+	stepIndex := f.GetWorkStepIndex(r.record.CurrentStep)
+	if stepIndex < 0 {
+		return fmt.Errorf("workflow %s has no step %s", f.Name, r.record.CurrentStep)
+	}
 
 	for stepIndex < len(f.WorkSteps) {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("workflow %s is cancelled: %w", f.Name, err)
+		}
+
+		step := f.WorkSteps[stepIndex]
+		name := step.GetName()
+		r.stepStatus[name] = WorkStepStatusProgressing
+		r.record.CurrentStep = name
+		if err := f.checkpoint(ctx, r); err != nil {
+			return err
+		}
+
+		previous := r.attempts[name]
+		delete(r.attempts, name)
+		next, err := f.executeStep(ctx, step, r.data, previous, func(attempt *StepAttempt) error {
+			r.result.History = append(r.result.History, attempt)
+			return f.checkpoint(ctx, r)
+		})
+		r.result.Path = append(r.result.Path, name)
+		if err != nil {
+			r.stepStatus[name] = WorkStepStatusFailed
+			return fmt.Errorf("workflow %s failed at step %s: %w", f.Name, name, err)
+		}
+		r.stepStatus[name] = WorkStepStatusCompleted
+
+		switch next {
+		case WorkStepNext:
+			stepIndex++
+		case WorkStepEnd:
+			stepIndex = len(f.WorkSteps)
+		default:
+			stepIndex = f.GetWorkStepIndex(next)
+			if stepIndex < 0 {
+				r.record.CurrentStep = next
+				return fmt.Errorf("workflow %s has no step %s, named after step %s", f.Name, next, name)
+			}
+		}
 
+		r.record.CurrentStep = ""
+		if stepIndex < len(f.WorkSteps) {
+			r.record.CurrentStep = f.WorkSteps[stepIndex].GetName()
+		}
+		if err := f.checkpoint(ctx, r); err != nil {
+			return err
+		}
 	}
 
 	return nil
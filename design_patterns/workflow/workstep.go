@@ -7,3 +7,14 @@ type WorkStep interface {
 	GetName() string
 	Process(ctx context.Context, context interface{}) (string, error)
 }
+
+// IdempotentStep is implemented by steps that can safely run again after being interrupted while running.
+// Resume only runs an interrupted step again if it is idempotent.
+type IdempotentStep interface {
+	IsIdempotent() bool
+}
+
+func isIdempotent(step WorkStep) bool {
+	idempotent, ok := step.(IdempotentStep)
+	return ok && idempotent.IsIdempotent()
+}
@@ -0,0 +1,386 @@
+package event_model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	logger "log/slog"
+	"sync"
+	"time"
+
+	"github.com/Algo2147483647/golang_toolkit/common"
+	"github.com/Algo2147483647/golang_toolkit/rule_engine"
+)
+
+// Default sizing of an EventBus
+const (
+	DefaultBusPartitions = 8
+	DefaultBusQueueSize  = 256
+)
+
+// Reasons of dead letters
+const (
+	DeadLetterReasonUnhandled    = "unhandled"      // no subscription matched the event
+	DeadLetterReasonFailed       = "handler_failed" // a matching subscription returned an error
+	DeadLetterReasonFilterFailed = "filter_failed"  // the filter of a subscription failed to evaluate
+)
+
+var (
+	ErrBusClosed = errors.New("event bus is closed")         // publishing to a closed bus
+	ErrBusFull   = errors.New("event bus partition is full") // TryPublish to a full partition
+)
+
+// BusEvent is an event published on the bus. Events with the same Key are delivered in the order
+// they were published; events without Key are ordered by Type.
+type BusEvent struct {
+	Type        string                 `json:"type"`
+	Key         string                 `json:"key"`
+	Attributes  map[string]interface{} `json:"attributes"`
+	PublishedAt time.Time              `json:"published_at"`
+}
+
+func (e *BusEvent) getOrderingKey() string {
+	if e.Key == "" {
+		return e.Type
+	}
+	return e.Key
+}
+
+// BusHandler handles the events of a subscription, calls for one subscription never overlap
+type BusHandler func(ctx context.Context, event *BusEvent) error
+
+// Subscription receives the events of the given types (all when empty) whose attributes match Filter
+type Subscription struct {
+	Name       string
+	EventTypes []string
+	Filter     rule_engine.NodeIf // optional, evaluated with the attributes as variables
+	Handler    BusHandler
+
+	mu sync.Mutex
+}
+
+// NewSubscription creates a subscription, filter is a rule_engine expression or empty
+func NewSubscription(name string, eventTypes []string, filter string, handler BusHandler) (*Subscription, error) {
+	subscription := &Subscription{Name: name, EventTypes: eventTypes, Handler: handler}
+	if filter != "" {
+		node, err := rule_engine.Compile(filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile filter of subscription %s: %w", name, err)
+		}
+		subscription.Filter = node
+	}
+	return subscription, nil
+}
+
+// Match reports whether the subscription receives the event, an error is returned when the filter
+// fails to evaluate or does not evaluate to a bool
+func (s *Subscription) Match(event *BusEvent) (bool, error) {
+	if len(s.EventTypes) > 0 && !common.Contains(s.EventTypes, event.Type) {
+		return false, nil
+	}
+	if s.Filter == nil {
+		return true, nil
+	}
+
+	env := rule_engine.NewEnvironment()
+	for name, value := range event.Attributes {
+		env.SetValue(name, value)
+	}
+
+	result, err := rule_engine.Evaluate(s.Filter, env)
+	if err != nil {
+		return false, fmt.Errorf("filter of subscription %s: %w", s.Name, err)
+	}
+	if result == nil {
+		return false, fmt.Errorf("filter of subscription %s evaluates to nothing", s.Name)
+	}
+	match, ok := result.GetValue().(bool)
+	if !ok {
+		return false, fmt.Errorf("filter of subscription %s evaluates to %v, not a bool", s.Name, result.GetValue())
+	}
+	return match, nil
+}
+
+func (s *Subscription) handle(ctx context.Context, event *BusEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Handler(ctx, event)
+}
+
+// DeadLetter is an event that was not handled
+type DeadLetter struct {
+	Event        *BusEvent `json:"event"`
+	Reason       string    `json:"reason"`
+	Subscription string    `json:"subscription,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// DeadLetterQueue receives the dead letters of a bus
+type DeadLetterQueue interface {
+	Push(ctx context.Context, letter *DeadLetter) error
+}
+
+// MemoryDeadLetterQueue keeps dead letters in memory
+type MemoryDeadLetterQueue struct {
+	mu      sync.RWMutex
+	letters []*DeadLetter
+}
+
+func NewMemoryDeadLetterQueue() *MemoryDeadLetterQueue {
+	return &MemoryDeadLetterQueue{}
+}
+
+func (q *MemoryDeadLetterQueue) Push(ctx context.Context, letter *DeadLetter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.letters = append(q.letters, letter)
+	return nil
+}
+
+// GetLetters returns a copy of the dead letters in the order they were pushed
+func (q *MemoryDeadLetterQueue) GetLetters() []*DeadLetter {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return append([]*DeadLetter(nil), q.letters...)
+}
+
+// EventBus is an in-process publish/subscribe bus. Events are spread over partitions by ordering key,
+// each partition has a bounded queue and delivers its events one at a time, so events with the same
+// key reach every subscription in publish order. Publish blocks while the queue of the partition is
+// full, which slows producers down to the pace of the subscribers.
+type EventBus struct {
+	Partitions  int
+	QueueSize   int
+	DeadLetters DeadLetterQueue
+	Clock       common.Clock
+
+	mu            sync.Mutex
+	started       bool
+	closed        bool
+	done          chan struct{} // closed by Close to release the blocked publishers
+	queues        []chan *BusEvent
+	sending       sync.WaitGroup // publishers that may still send to the queues
+	wg            sync.WaitGroup
+	subMu         sync.RWMutex // guards subscriptions
+	subscriptions []*Subscription
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		Partitions:  DefaultBusPartitions,
+		QueueSize:   DefaultBusQueueSize,
+		DeadLetters: NewMemoryDeadLetterQueue(),
+		Clock:       common.SystemClock{},
+	}
+}
+
+// Start starts the partition workers, ctx is given to the handlers. It is called by the first Publish
+// if needed, with a background context.
+func (b *EventBus) Start(ctx context.Context) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.start(ctx)
+}
+
+func (b *EventBus) start(ctx context.Context) {
+	if b.started || b.closed {
+		return
+	}
+	b.started = true
+	b.done = make(chan struct{})
+
+	partitions := max(b.Partitions, 1)
+	b.queues = make([]chan *BusEvent, partitions)
+	for i := range b.queues {
+		b.queues[i] = make(chan *BusEvent, max(b.QueueSize, 1))
+
+		b.wg.Add(1)
+		go func(queue chan *BusEvent) {
+			defer b.wg.Done()
+			for event := range queue {
+				b.deliver(ctx, event)
+			}
+		}(b.queues[i])
+	}
+}
+
+// Subscribe adds the subscription, it receives the events published from now on
+func (b *EventBus) Subscribe(subscription *Subscription) error {
+	if subscription.Handler == nil {
+		return fmt.Errorf("subscription %s has no handler", subscription.Name)
+	}
+
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	b.subscriptions = append(b.subscriptions, subscription)
+	return nil
+}
+
+// Unsubscribe removes the subscription, events already queued may still reach it
+func (b *EventBus) Unsubscribe(subscription *Subscription) {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	result := make([]*Subscription, 0, len(b.subscriptions))
+	for _, item := range b.subscriptions {
+		if item != subscription {
+			result = append(result, item)
+		}
+	}
+	b.subscriptions = result
+}
+
+// Publish queues the event, blocking while its partition is full or until ctx is done. A handler
+// publishing to its own bus must use TryPublish: its partition is not delivered while the handler
+// runs, so a Publish to the full partition would never return.
+func (b *EventBus) Publish(ctx context.Context, event *BusEvent) error {
+	return b.publish(ctx, event, true)
+}
+
+// TryPublish queues the event like Publish, but returns ErrBusFull instead of blocking when the
+// partition of the event is full
+func (b *EventBus) TryPublish(ctx context.Context, event *BusEvent) error {
+	return b.publish(ctx, event, false)
+}
+
+func (b *EventBus) publish(ctx context.Context, event *BusEvent, block bool) error {
+	queue, err := b.getQueue(event)
+	if err != nil {
+		return err
+	}
+	defer b.sending.Done()
+
+	if !block {
+		select {
+		case queue <- event:
+			return nil
+		default:
+			return ErrBusFull
+		}
+	}
+
+	select {
+	case queue <- event:
+		return nil
+	case <-b.done:
+		return ErrBusClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// getQueue returns the queue of the partition of the event, starting the bus if needed. The caller
+// must call b.sending.Done once it no longer sends to the queue.
+func (b *EventBus) getQueue(event *BusEvent) (chan *BusEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+	b.start(context.Background())
+	b.sending.Add(1)
+
+	if event.PublishedAt.IsZero() {
+		event.PublishedAt = common.GetClock(b.Clock).Now()
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(event.getOrderingKey()))
+	return b.queues[hash.Sum32()%uint32(len(b.queues))], nil
+}
+
+// Close stops accepting events and returns once the queued events were delivered. Publishers blocked
+// on a full partition return ErrBusClosed. A bus closed before it started never starts.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	started := b.started
+	if started {
+		close(b.done)
+	}
+	b.mu.Unlock()
+
+	if !started {
+		return
+	}
+
+	// no publisher sends once closed is set and the blocked ones were released, so the queues can be closed
+	b.sending.Wait()
+	for _, queue := range b.queues {
+		close(queue)
+	}
+	b.wg.Wait()
+}
+
+// deliver hands the event to every matching subscription, unhandled events and failures are dead letters
+func (b *EventBus) deliver(ctx context.Context, event *BusEvent) {
+	b.subMu.RLock()
+	subscriptions := append([]*Subscription(nil), b.subscriptions...)
+	b.subMu.RUnlock()
+
+	handled := false
+	for _, subscription := range subscriptions {
+		match, err := subscription.Match(event)
+		if err != nil {
+			// the event may have been meant for the subscription, so it is not reported unhandled too
+			handled = true
+			b.deadLetter(ctx, &DeadLetter{Event: event, Reason: DeadLetterReasonFilterFailed, Subscription: subscription.Name, Error: err.Error()})
+			continue
+		}
+		if !match {
+			continue
+		}
+		handled = true
+
+		if err := subscription.handle(ctx, event); err != nil {
+			b.deadLetter(ctx, &DeadLetter{Event: event, Reason: DeadLetterReasonFailed, Subscription: subscription.Name, Error: err.Error()})
+		}
+	}
+
+	if !handled {
+		b.deadLetter(ctx, &DeadLetter{Event: event, Reason: DeadLetterReasonUnhandled})
+	}
+}
+
+func (b *EventBus) deadLetter(ctx context.Context, letter *DeadLetter) {
+	if b.DeadLetters == nil {
+		return
+	}
+
+	letter.Time = common.GetClock(b.Clock).Now()
+	if err := b.DeadLetters.Push(ctx, letter); err != nil {
+		logger.ErrorContext(ctx, fmt.Sprintf("failed to push dead letter of event %s: %v", letter.Event.Type, err))
+	}
+}
+
+// NewUnitGroupHandler runs the events it receives on the instance, with the attributes as payload.
+// The instance is checkpointed to store when it is set.
+func NewUnitGroupHandler(instance *UnitGroupInstance, scheduler *Scheduler, store InstanceStore) BusHandler {
+	if scheduler == nil {
+		scheduler = NewScheduler()
+	}
+
+	return func(ctx context.Context, event *BusEvent) error {
+		if store != nil {
+			_, err := instance.Run(ctx, scheduler, store, []string{event.Type}, event.Attributes)
+			return err
+		}
+
+		runScheduler := *scheduler
+		runScheduler.InstanceKey = instance.Key
+		_, err := runScheduler.Run(ctx, instance.GetUnits(), []string{event.Type}, event.Attributes)
+		return err
+	}
+}
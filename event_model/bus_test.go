@@ -0,0 +1,81 @@
+package event_model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBusCloseReleasesPublishers(t *testing.T) {
+	bus := NewEventBus()
+	bus.Partitions, bus.QueueSize = 1, 1
+
+	started, release := make(chan struct{}), make(chan struct{})
+	handlerErr := make(chan error, 1)
+	subscription, err := NewSubscription("s", nil, "", func(ctx context.Context, event *BusEvent) error {
+		if event.Type != "first" {
+			return nil
+		}
+		close(started)
+		<-release
+		handlerErr <- bus.TryPublish(ctx, &BusEvent{Type: "from handler"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewSubscription: %v", err)
+	}
+	if err := bus.Subscribe(subscription); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := bus.Publish(ctx, &BusEvent{Type: "first"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	<-started
+	if err := bus.Publish(ctx, &BusEvent{Type: "queued"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	blocked := make(chan error, 1)
+	go func() { blocked <- bus.Publish(ctx, &BusEvent{Type: "blocked"}) }()
+
+	closed := make(chan struct{})
+	go func() {
+		bus.Close()
+		close(closed)
+	}()
+
+	select {
+	case err := <-blocked:
+		if !errors.Is(err, ErrBusClosed) {
+			t.Errorf("blocked Publish error = %v, want %v", err, ErrBusClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not release the blocked Publish")
+	}
+
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return while a handler published")
+	}
+	if err := <-handlerErr; !errors.Is(err, ErrBusClosed) {
+		t.Errorf("TryPublish from the handler error = %v, want %v", err, ErrBusClosed)
+	}
+}
+
+func TestBusCloseBeforeStart(t *testing.T) {
+	bus := NewEventBus()
+	bus.Close()
+
+	if err := bus.Publish(context.Background(), &BusEvent{Type: "event"}); !errors.Is(err, ErrBusClosed) {
+		t.Errorf("Publish error = %v, want %v", err, ErrBusClosed)
+	}
+	bus.Start(context.Background())
+	if bus.started || len(bus.queues) != 0 {
+		t.Error("a closed bus started its workers")
+	}
+}
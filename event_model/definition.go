@@ -0,0 +1,164 @@
+package event_model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Algo2147483647/golang_toolkit/event_model/eca"
+)
+
+// UnitDefinition declares a unit, its Pre and Post units are referenced by key
+type UnitDefinition struct {
+	eca.Definition `yaml:",inline"`
+	Key            string                 `json:"key" yaml:"key"`
+	Pre            []string               `json:"pre" yaml:"pre"`
+	Post           []string               `json:"post" yaml:"post"`
+	Params         map[string]interface{} `json:"params" yaml:"params"`
+	Join           string                 `json:"join" yaml:"join"`             // JoinTypeAll when empty
+	JoinCount      int                    `json:"join_count" yaml:"join_count"` // for JoinTypeNOfM
+
+	Timeout         string   `json:"timeout" yaml:"timeout"` // a time.ParseDuration string, e.g. 15m
+	TimeoutPost     []string `json:"timeout_post" yaml:"timeout_post"`
+	EscalationEvent string   `json:"escalation_event" yaml:"escalation_event"`
+}
+
+// UnitGroupDefinition declares a unit group. It carries json and yaml tags: LoadUnitGroupJSON decodes
+// it with encoding/json, LoadUnitGroupYAML with the YAML decoder given by the caller.
+type UnitGroupDefinition struct {
+	Key    string                 `json:"key" yaml:"key"`
+	Units  []*UnitDefinition      `json:"units" yaml:"units"`
+	Params map[string]interface{} `json:"params" yaml:"params"`
+}
+
+// LoadUnitGroupJSON decodes a unit group definition from JSON and builds it
+func LoadUnitGroupJSON(ctx context.Context, data []byte, registry *eca.Registry) (*UnitGroup, error) {
+	def := &UnitGroupDefinition{}
+	if err := json.Unmarshal(data, def); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal unit group definition: %w", err)
+	}
+	return BuildUnitGroup(ctx, def, registry)
+}
+
+// Unmarshaler decodes data into v, e.g. yaml.Unmarshal
+type Unmarshaler func(data []byte, v interface{}) error
+
+// LoadUnitGroupYAML decodes a unit group definition from YAML with unmarshal and builds it. The module
+// has no YAML dependency, so unmarshal comes from a YAML library honoring the yaml tags and decoding
+// mappings as map[string]interface{}, such as yaml.Unmarshal of gopkg.in/yaml.v3.
+func LoadUnitGroupYAML(ctx context.Context, data []byte, registry *eca.Registry, unmarshal Unmarshaler) (*UnitGroup, error) {
+	if unmarshal == nil {
+		return nil, fmt.Errorf("no YAML decoder to load the unit group definition")
+	}
+
+	def := &UnitGroupDefinition{}
+	if err := unmarshal(data, def); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal unit group definition: %w", err)
+	}
+	return BuildUnitGroup(ctx, def, registry)
+}
+
+// BuildUnitGroup builds the units of the definition with the registry and links them. An edge may
+// be declared on either side (Post of one unit or Pre of the other). Every ECA is validated with
+// ECA.Check, and the group with UnitGroup.Validate.
+func BuildUnitGroup(ctx context.Context, def *UnitGroupDefinition, registry *eca.Registry) (*UnitGroup, error) {
+	group := &UnitGroup{
+		Key:    def.Key,
+		Params: def.Params,
+	}
+	units := make(map[string]*Unit, len(def.Units))
+
+	for _, item := range def.Units {
+		if item.Key == "" {
+			return nil, fmt.Errorf("unit group %s: unit has no key", def.Key)
+		}
+		if _, ok := units[item.Key]; ok {
+			return nil, fmt.Errorf("unit group %s: duplicate unit %s", def.Key, item.Key)
+		}
+
+		rule, err := registry.Build(ctx, &item.Definition)
+		if err != nil {
+			return nil, fmt.Errorf("unit %s: %w", item.Key, err)
+		}
+
+		join, err := NewJoinPolicy(item.Join, item.JoinCount)
+		if err != nil {
+			return nil, fmt.Errorf("unit %s: %w", item.Key, err)
+		}
+
+		var timeout time.Duration
+		if item.Timeout != "" {
+			if timeout, err = time.ParseDuration(item.Timeout); err != nil {
+				return nil, fmt.Errorf("unit %s: invalid timeout: %w", item.Key, err)
+			}
+		}
+
+		unit := &Unit{
+			ECA:             *rule,
+			Key:             item.Key,
+			State:           UnitStateNotStarted,
+			Params:          item.Params,
+			Join:            join,
+			Timeout:         timeout,
+			EscalationEvent: item.EscalationEvent,
+		}
+		units[item.Key] = unit
+		group.Units = append(group.Units, unit)
+	}
+
+	link := func(from, to string, timeout bool) error {
+		pre, ok := units[from]
+		if !ok {
+			return fmt.Errorf("unit group %s: unknown unit %s", def.Key, from)
+		}
+		post, ok := units[to]
+		if !ok {
+			return fmt.Errorf("unit group %s: unknown unit %s", def.Key, to)
+		}
+		if timeout {
+			if !containsUnit(pre.TimeoutPost, post) {
+				pre.TimeoutPost = append(pre.TimeoutPost, post)
+			}
+		} else if !containsUnit(pre.Post, post) {
+			pre.Post = append(pre.Post, post)
+		}
+		if !containsUnit(post.Pre, pre) {
+			post.Pre = append(post.Pre, pre)
+		}
+		return nil
+	}
+
+	for _, item := range def.Units {
+		for _, key := range item.Post {
+			if err := link(item.Key, key, false); err != nil {
+				return nil, err
+			}
+		}
+		for _, key := range item.TimeoutPost {
+			if err := link(item.Key, key, true); err != nil {
+				return nil, err
+			}
+		}
+		for _, key := range item.Pre {
+			if err := link(key, item.Key, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := group.Validate(); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func containsUnit(units []*Unit, unit *Unit) bool {
+	for _, item := range units {
+		if item == unit {
+			return true
+		}
+	}
+	return false
+}
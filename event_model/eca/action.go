@@ -1,15 +1,55 @@
 package eca
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Action interface {
 	Execute(ctx context.Context) error
 	GetAttributes(ctx context.Context) []string
 }
 
+// Compensator is implemented by actions that can undo their effect, it is called when a later
+// action of the same ECA fails
+type Compensator interface {
+	Compensate(ctx context.Context) error
+}
+
+// Retryable is implemented by actions with a retry policy
+type Retryable interface {
+	GetRetryPolicy(ctx context.Context) *RetryPolicy
+}
+
+// RetryPolicy retries a failed action with an exponential backoff
+type RetryPolicy struct {
+	MaxAttempts    int           `json:"max_attempts" yaml:"max_attempts"` // including the first one, 0 or 1 means no retry
+	InitialBackoff time.Duration `json:"initial_backoff" yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff" yaml:"max_backoff"` // 0 means unbounded
+	Multiplier     float64       `json:"multiplier" yaml:"multiplier"`   // 0 means 2
+}
+
+// Backoff returns the delay before the given retry, starting from 1
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		backoff *= multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+	return time.Duration(backoff)
+}
+
 type ActionBase struct {
-	Attributes []string `json:"attributes"`
-	Params     map[string]interface{}
+	Attributes  []string `json:"attributes"`
+	Params      map[string]interface{}
+	RetryPolicy *RetryPolicy `json:"retry_policy"`
 }
 
 func (c *ActionBase) Execute(ctx context.Context) error {
@@ -19,3 +59,7 @@ func (c *ActionBase) Execute(ctx context.Context) error {
 func (c *ActionBase) GetAttributes(ctx context.Context) []string {
 	return c.Attributes
 }
+
+func (c *ActionBase) GetRetryPolicy(ctx context.Context) *RetryPolicy {
+	return c.RetryPolicy
+}
@@ -2,11 +2,18 @@ package eca
 
 import (
 	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Algo2147483647/golang_toolkit/common"
 	"github.com/Algo2147483647/golang_toolkit/rule_engine"
 )
 
+// ErrConditionNotBool is returned when a condition evaluates to something else than a bool
+var ErrConditionNotBool = errors.New("condition did not evaluate to a bool")
+
 type Condition interface {
-	IsPass(ctx context.Context) bool
+	IsPass(ctx context.Context, env *rule_engine.Environment) (bool, error)
 	GetAttributes(ctx context.Context) []string
 }
 
@@ -15,14 +22,83 @@ type ConditionBase struct {
 	Node       rule_engine.NodeIf `json:"node"`
 }
 
-func (c *ConditionBase) IsPass(ctx context.Context) bool {
-	result, err := c.Node.Evaluate()
+// TracedCondition is implemented by conditions that can explain their result
+type TracedCondition interface {
+	IsPassWithTrace(ctx context.Context, env *rule_engine.Environment) (bool, *rule_engine.Trace, error)
+}
+
+// ConditionResult records the evaluation of one condition
+type ConditionResult struct {
+	Index int                `json:"index"`
+	Pass  bool               `json:"pass"`
+	Error string             `json:"error,omitempty"`
+	Trace *rule_engine.Trace `json:"trace,omitempty"`
+}
+
+// IsPass evaluates the condition node against env. An evaluation error or a non-bool result is
+// returned as an error instead of failing the condition silently.
+func (c *ConditionBase) IsPass(ctx context.Context, env *rule_engine.Environment) (bool, error) {
+	if c.Node == nil {
+		return false, fmt.Errorf("condition has no node")
+	}
+
+	result, err := rule_engine.Evaluate(c.Node, env)
+	return c.toPass(result, err)
+}
+
+// IsPassWithTrace is IsPass also returning the trace of the evaluation
+func (c *ConditionBase) IsPassWithTrace(ctx context.Context, env *rule_engine.Environment) (bool, *rule_engine.Trace, error) {
+	if c.Node == nil {
+		return false, nil, fmt.Errorf("condition has no node")
+	}
+
+	result, trace, err := rule_engine.EvaluateWithTrace(c.Node, env)
+	pass, err := c.toPass(result, err)
+	return pass, trace, err
+}
+
+func (c *ConditionBase) toPass(result rule_engine.ValueIf, err error) (bool, error) {
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate condition %s: %w", c.String(), err)
+	}
+
+	if result != nil {
+		if pass, ok := result.GetValue().(bool); ok {
+			return pass, nil
+		}
+		return false, fmt.Errorf("%w: condition %s returned %v of type %s", ErrConditionNotBool, c.String(), result.GetValue(), result.GetType())
+	}
+	return false, fmt.Errorf("%w: condition %s returned nothing", ErrConditionNotBool, c.String())
+}
+
+// String returns the expression of the condition, for error messages
+func (c *ConditionBase) String() string {
+	if c.Node == nil {
+		return "<nil>"
+	}
+	expr, err := rule_engine.ToExpression(c.Node)
 	if err != nil {
-		return false
+		return "<unprintable>"
 	}
-	return result.GetValue().(bool)
+	return expr
 }
 
+// GetAttributes returns the variables read by the condition node, together with the declared Attributes.
+// The payload variable is left out, it is always provided.
 func (c *ConditionBase) GetAttributes(ctx context.Context) []string {
-	return c.Attributes
+	result := make([]string, 0)
+	if c.Node != nil {
+		for _, attr := range rule_engine.Analyze(c.Node).Variables {
+			if attr != PayloadVariable {
+				result = append(result, attr)
+			}
+		}
+	}
+
+	for _, attr := range c.Attributes {
+		if !common.Contains(result, attr) {
+			result = append(result, attr)
+		}
+	}
+	return result
 }
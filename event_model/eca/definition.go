@@ -0,0 +1,222 @@
+package eca
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Algo2147483647/golang_toolkit/rule_engine"
+)
+
+// EventDefinition declares an event by its type
+type EventDefinition struct {
+	Type       string                 `json:"type" yaml:"type"`
+	Attributes map[string]interface{} `json:"attributes" yaml:"attributes"`
+	Params     map[string]interface{} `json:"params" yaml:"params"`
+}
+
+// ConditionDefinition declares a condition as rule_engine expression text
+type ConditionDefinition struct {
+	Expression string   `json:"expression" yaml:"expression"`
+	Attributes []string `json:"attributes" yaml:"attributes"`
+}
+
+// ActionDefinition declares an action by its registered name, RetryPolicy overrides the policy of the built action
+type ActionDefinition struct {
+	Name        string                 `json:"name" yaml:"name"`
+	Attributes  []string               `json:"attributes" yaml:"attributes"`
+	Params      map[string]interface{} `json:"params" yaml:"params"`
+	RetryPolicy *RetryPolicyDefinition `json:"retry_policy" yaml:"retry_policy"`
+}
+
+// RetryPolicyDefinition declares a RetryPolicy, the backoffs are time.ParseDuration strings, e.g. 500ms
+type RetryPolicyDefinition struct {
+	MaxAttempts    int     `json:"max_attempts" yaml:"max_attempts"`
+	InitialBackoff string  `json:"initial_backoff" yaml:"initial_backoff"`
+	MaxBackoff     string  `json:"max_backoff" yaml:"max_backoff"`
+	Multiplier     float64 `json:"multiplier" yaml:"multiplier"`
+}
+
+// Build parses the backoffs of the definition
+func (d *RetryPolicyDefinition) Build() (*RetryPolicy, error) {
+	policy := &RetryPolicy{MaxAttempts: d.MaxAttempts, Multiplier: d.Multiplier}
+
+	var err error
+	if d.InitialBackoff != "" {
+		if policy.InitialBackoff, err = time.ParseDuration(d.InitialBackoff); err != nil {
+			return nil, fmt.Errorf("invalid initial backoff: %w", err)
+		}
+	}
+	if d.MaxBackoff != "" {
+		if policy.MaxBackoff, err = time.ParseDuration(d.MaxBackoff); err != nil {
+			return nil, fmt.Errorf("invalid max backoff: %w", err)
+		}
+	}
+	return policy, nil
+}
+
+// Definition is the serializable form of an ECA, turned into concrete types by a Registry
+type Definition struct {
+	Events     []*EventDefinition     `json:"event" yaml:"event"`
+	Conditions []*ConditionDefinition `json:"condition" yaml:"condition"`
+	Actions    []*ActionDefinition    `json:"action" yaml:"action"`
+}
+
+// EventFactory builds an event from its definition
+type EventFactory func(def *EventDefinition) (Event, error)
+
+// ActionFactory builds an action from its definition
+type ActionFactory func(def *ActionDefinition) (Action, error)
+
+// Registry maps event types and action names to the factories building them.
+// Events of an unregistered type are built as EventBase, actions must always be registered.
+type Registry struct {
+	mu      sync.RWMutex
+	events  map[string]EventFactory
+	actions map[string]ActionFactory
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		events:  make(map[string]EventFactory),
+		actions: make(map[string]ActionFactory),
+	}
+}
+
+func (r *Registry) RegisterEvent(eventType string, factory EventFactory) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events[eventType] = factory
+	return r
+}
+
+func (r *Registry) RegisterAction(name string, factory ActionFactory) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.actions[name] = factory
+	return r
+}
+
+// BuildEvent builds the event with the factory registered for its type
+func (r *Registry) BuildEvent(def *EventDefinition) (Event, error) {
+	if def.Type == "" {
+		return nil, fmt.Errorf("event has no type")
+	}
+
+	r.mu.RLock()
+	factory, ok := r.events[def.Type]
+	r.mu.RUnlock()
+
+	if !ok {
+		return &EventBase{Type: def.Type, Attributes: def.Attributes}, nil
+	}
+
+	event, err := factory(def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build event %s: %w", def.Type, err)
+	}
+	return event, nil
+}
+
+// BuildCondition compiles the expression of the condition
+func (r *Registry) BuildCondition(def *ConditionDefinition) (Condition, error) {
+	node, err := rule_engine.Compile(def.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile condition %q: %w", def.Expression, err)
+	}
+	return &ConditionBase{Attributes: def.Attributes, Node: node}, nil
+}
+
+// BuildAction builds the action with the factory registered for its name. The RetryPolicy of the
+// definition, if any, is applied by wrapping the action, which keeps it a Compensator.
+func (r *Registry) BuildAction(def *ActionDefinition) (Action, error) {
+	r.mu.RLock()
+	factory, ok := r.actions[def.Name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("action %q is not registered", def.Name)
+	}
+
+	var policy *RetryPolicy
+	if def.RetryPolicy != nil {
+		var err error
+		if policy, err = def.RetryPolicy.Build(); err != nil {
+			return nil, fmt.Errorf("action %s: %w", def.Name, err)
+		}
+	}
+
+	action, err := factory(def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build action %s: %w", def.Name, err)
+	}
+
+	if policy == nil {
+		return action, nil
+	}
+	if compensator, ok := action.(Compensator); ok {
+		return &compensableRetryAction{retryAction: retryAction{Action: action, policy: policy}, compensator: compensator}, nil
+	}
+	return &retryAction{Action: action, policy: policy}, nil
+}
+
+// retryAction gives an action the retry policy of its definition
+type retryAction struct {
+	Action
+	policy *RetryPolicy
+}
+
+func (a *retryAction) GetRetryPolicy(ctx context.Context) *RetryPolicy {
+	return a.policy
+}
+
+// Unwrap returns the action built by the factory
+func (a *retryAction) Unwrap() Action {
+	return a.Action
+}
+
+type compensableRetryAction struct {
+	retryAction
+	compensator Compensator
+}
+
+func (a *compensableRetryAction) Compensate(ctx context.Context) error {
+	return a.compensator.Compensate(ctx)
+}
+
+// Build turns the definition into an ECA and validates it
+func (r *Registry) Build(ctx context.Context, def *Definition) (*ECA, error) {
+	result := &ECA{}
+
+	for _, item := range def.Events {
+		event, err := r.BuildEvent(item)
+		if err != nil {
+			return nil, err
+		}
+		result.Events = append(result.Events, event)
+	}
+
+	for _, item := range def.Conditions {
+		condition, err := r.BuildCondition(item)
+		if err != nil {
+			return nil, err
+		}
+		result.Conditions = append(result.Conditions, condition)
+	}
+
+	for _, item := range def.Actions {
+		action, err := r.BuildAction(item)
+		if err != nil {
+			return nil, err
+		}
+		result.Actions = append(result.Actions, action)
+	}
+
+	if err := result.Validate(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
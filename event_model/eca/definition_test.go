@@ -0,0 +1,81 @@
+package eca
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyAction fails until it ran failures times
+type flakyAction struct {
+	ActionBase
+	failures    int
+	calls       int
+	compensated bool
+}
+
+func (a *flakyAction) Execute(ctx context.Context) error {
+	a.calls++
+	if a.calls <= a.failures {
+		return errors.New("flaky")
+	}
+	return nil
+}
+
+func (a *flakyAction) Compensate(ctx context.Context) error {
+	a.compensated = true
+	return nil
+}
+
+func TestBuildActionRetryPolicy(t *testing.T) {
+	flaky := &flakyAction{failures: 2}
+	registry := NewRegistry().RegisterAction("flaky", func(def *ActionDefinition) (Action, error) {
+		return flaky, nil
+	})
+
+	action, err := registry.BuildAction(&ActionDefinition{
+		Name:        "flaky",
+		RetryPolicy: &RetryPolicyDefinition{MaxAttempts: 3, InitialBackoff: "1ms", MaxBackoff: "2ms"},
+	})
+	if err != nil {
+		t.Fatalf("BuildAction: %v", err)
+	}
+
+	retryable, ok := action.(Retryable)
+	if !ok {
+		t.Fatal("built action is not Retryable")
+	}
+	if policy := retryable.GetRetryPolicy(context.Background()); policy.MaxAttempts != 3 || policy.InitialBackoff != time.Millisecond || policy.MaxBackoff != 2*time.Millisecond {
+		t.Errorf("retry policy = %+v", policy)
+	}
+	if _, ok := action.(Compensator); !ok {
+		t.Error("built action lost Compensator")
+	}
+
+	report, err := (&ECA{Actions: []Action{action}}).ExecuteActions(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteActions: %v", err)
+	}
+	if flaky.calls != 3 || report.Outcomes[0].Attempts != 3 {
+		t.Errorf("action ran %d times in %d attempts, want 3", flaky.calls, report.Outcomes[0].Attempts)
+	}
+}
+
+func TestBuildActionInvalidRetryPolicy(t *testing.T) {
+	registry := NewRegistry().RegisterAction("noop", func(def *ActionDefinition) (Action, error) {
+		return &ActionBase{}, nil
+	})
+
+	if _, err := registry.BuildAction(&ActionDefinition{Name: "noop", RetryPolicy: &RetryPolicyDefinition{InitialBackoff: "soon"}}); err == nil {
+		t.Error("BuildAction with an invalid backoff succeeded, want an error")
+	}
+
+	action, err := registry.BuildAction(&ActionDefinition{Name: "noop"})
+	if err != nil {
+		t.Fatalf("BuildAction: %v", err)
+	}
+	if _, ok := action.(*ActionBase); !ok {
+		t.Errorf("action without retry policy is %T, want it unwrapped", action)
+	}
+}
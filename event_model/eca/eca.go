@@ -2,7 +2,9 @@ package eca
 
 import (
 	"context"
+	"fmt"
 	"github.com/Algo2147483647/golang_toolkit/common"
+	"github.com/Algo2147483647/golang_toolkit/rule_engine"
 )
 
 type ECA struct {
@@ -11,55 +13,190 @@ type ECA struct {
 	Actions    []Action    `json:"action"`
 }
 
+// Check verifies that every attribute read by a condition or an action is provided by an event,
+// or is the PayloadVariable
 func (eca *ECA) Check(ctx context.Context) bool {
+	return eca.Validate(ctx) == nil
+}
+
+// Validate is Check reporting the first attribute that no event provides
+func (eca *ECA) Validate(ctx context.Context) error {
+	provided := map[string]bool{PayloadVariable: true}
 	for _, event := range eca.Events {
-		attrsKeys := common.GetKeys(event.GetAttributes(ctx))
+		for _, attr := range common.GetKeys(event.GetAttributes(ctx)) {
+			provided[attr] = true
+		}
+	}
 
-		for _, cond := range eca.Conditions {
-			for _, attr := range attrsKeys {
-				if !common.Contains(cond.GetAttributes(ctx), attr) {
-					return false
-				}
+	for _, cond := range eca.Conditions {
+		for _, attr := range cond.GetAttributes(ctx) {
+			if !provided[attr] {
+				return fmt.Errorf("condition reads attribute %s which no event provides", attr)
 			}
 		}
+	}
 
-		for _, action := range eca.Actions {
-			for _, attr := range attrsKeys {
-				if !common.Contains(action.GetAttributes(ctx), attr) {
-					return false
-				}
+	for _, action := range eca.Actions {
+		for _, attr := range action.GetAttributes(ctx) {
+			if !provided[attr] {
+				return fmt.Errorf("action reads attribute %s which no event provides", attr)
 			}
 		}
 	}
 
-	return true
+	return nil
 }
 
 func (eca *ECA) EventTrigger(ctx context.Context, req interface{}) bool {
+	return eca.Trigger(ctx, req) != nil
+}
+
+// Trigger is an incoming event together with the events of an ECA it triggered
+type Trigger struct {
+	Request *EventRequest
+	Events  []Event
+}
+
+// Trigger checks every event against req and returns the events it triggered, nil if there is none
+func (eca *ECA) Trigger(ctx context.Context, req interface{}) *Trigger {
+	request := GetEventRequest(req)
+	events := make([]Event, 0)
 	for _, event := range eca.Events {
-		if event.IsTrigger(ctx, req) {
-			return true
+		if event.IsTrigger(ctx, request) {
+			events = append(events, event)
 		}
 	}
-	return false
+
+	if len(events) == 0 {
+		return nil
+	}
+	return &Trigger{Request: request, Events: events}
 }
 
-func (eca *ECA) ConditionPass(ctx context.Context, req interface{}) bool {
-	for _, condition := range eca.Conditions {
-		if !condition.IsPass(ctx) {
-			return false
+// Fire commits the firing of the triggered events implementing FiringEvent
+func (t *Trigger) Fire(ctx context.Context) {
+	for _, event := range t.Events {
+		if firing, ok := event.(FiringEvent); ok {
+			firing.Fire(ctx)
 		}
 	}
+}
+
+// Clone copies the ECA for a new instance: the slices are copied and the events implementing
+// EventCloner are cloned, conditions and actions are shared and must not hold per-instance state
+func (eca *ECA) Clone() ECA {
+	result := ECA{
+		Events:     make([]Event, len(eca.Events)),
+		Conditions: append([]Condition(nil), eca.Conditions...),
+		Actions:    append([]Action(nil), eca.Actions...),
+	}
 
-	return true
+	for i, event := range eca.Events {
+		if cloner, ok := event.(EventCloner); ok {
+			result.Events[i] = cloner.CloneEvent()
+		} else {
+			result.Events[i] = event
+		}
+	}
+	return result
 }
 
-func (eca *ECA) ActionExecute(ctx context.Context) error {
-	for _, action := range eca.Actions {
-		err := action.Execute(ctx)
+// PayloadVariable is the variable holding the whole request payload in condition expressions
+const PayloadVariable = "req"
+
+// NewEnvironment builds the environment conditions are evaluated against: the attributes of the
+// events triggered by req, then the fields of the payload when it is a map[string]interface{}
+// (overriding the event attributes), and the payload itself as PayloadVariable.
+// req is a *Trigger returned by Trigger, or an incoming event that the events are checked against.
+func (eca *ECA) NewEnvironment(ctx context.Context, req interface{}) *rule_engine.Environment {
+	trigger, ok := req.(*Trigger)
+	if !ok {
+		trigger = eca.Trigger(ctx, req)
+	}
+	if trigger == nil {
+		return setPayload(rule_engine.NewEnvironment(), GetEventRequest(req).Payload)
+	}
+
+	env := rule_engine.NewEnvironment()
+	for _, event := range trigger.Events {
+		for name, value := range event.GetAttributes(ctx) {
+			env.SetValue(name, value)
+		}
+	}
+
+	return setPayload(env, trigger.Request.Payload)
+}
+
+// setPayload sets the fields of a map[string]interface{} payload and the payload itself as PayloadVariable
+func setPayload(env *rule_engine.Environment, req interface{}) *rule_engine.Environment {
+	if payload, ok := req.(map[string]interface{}); ok {
+		for name, value := range payload {
+			env.SetValue(name, value)
+		}
+	}
+
+	if req != nil {
+		env.SetValue(PayloadVariable, req)
+	}
+	return env
+}
+
+// ConditionPass reports whether all conditions pass for req, see NewEnvironment.
+// An error is returned if a condition cannot be evaluated or does not evaluate to a bool.
+func (eca *ECA) ConditionPass(ctx context.Context, req interface{}) (bool, error) {
+	if len(eca.Conditions) == 0 {
+		return true, nil
+	}
+
+	env := eca.NewEnvironment(ctx, req)
+	for i, condition := range eca.Conditions {
+		pass, err := condition.IsPass(ctx, env)
 		if err != nil {
-			return err
+			return false, fmt.Errorf("condition %d: %w", i, err)
+		}
+		if !pass {
+			return false, nil
 		}
 	}
-	return nil
+
+	return true, nil
+}
+
+// EvaluateConditions is ConditionPass also returning the result of every evaluated condition,
+// traced when the condition implements TracedCondition
+func (eca *ECA) EvaluateConditions(ctx context.Context, req interface{}) (bool, []*ConditionResult, error) {
+	results := make([]*ConditionResult, 0, len(eca.Conditions))
+	if len(eca.Conditions) == 0 {
+		return true, results, nil
+	}
+
+	env := eca.NewEnvironment(ctx, req)
+	for i, condition := range eca.Conditions {
+		result := &ConditionResult{Index: i}
+		results = append(results, result)
+
+		var err error
+		if traced, ok := condition.(TracedCondition); ok {
+			result.Pass, result.Trace, err = traced.IsPassWithTrace(ctx, env)
+		} else {
+			result.Pass, err = condition.IsPass(ctx, env)
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+			return false, results, fmt.Errorf("condition %d: %w", i, err)
+		}
+		if !result.Pass {
+			return false, results, nil
+		}
+	}
+
+	return true, results, nil
+}
+
+// ActionExecute executes the actions and returns the error only, ExecuteActions also returns the
+// outcome of every action
+func (eca *ECA) ActionExecute(ctx context.Context) error {
+	_, err := eca.ExecuteActions(ctx)
+	return err
 }
@@ -8,6 +8,42 @@ type Event interface {
 	IsTrigger(ctx context.Context, req interface{}) bool
 }
 
+// EventRequest is what events are checked against: the type of the incoming event and its payload
+type EventRequest struct {
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+
+	// UnitState looks up the state of the units run together with the event, it is set by the scheduler
+	UnitState UnitStateLookup `json:"-"`
+}
+
+// EventCloner is implemented by events holding state of their own (e.g. whether a timer fired).
+// They are cloned when a unit group is instantiated, other events are shared by all instances.
+type EventCloner interface {
+	CloneEvent() Event
+}
+
+// FiringEvent is implemented by events consumed when they fire, e.g. a one-shot timer. Their IsTrigger
+// only checks that they are due, the firing is committed with Fire once the unit passed its conditions
+// and executed its actions, so an event whose unit did not pass, or whose actions failed, fires again.
+type FiringEvent interface {
+	Fire(ctx context.Context)
+}
+
+// GetEventRequest normalizes the req given to IsTrigger, a plain string is an event type without payload
+func GetEventRequest(req interface{}) *EventRequest {
+	switch r := req.(type) {
+	case *EventRequest:
+		return r
+	case EventRequest:
+		return &r
+	case string:
+		return &EventRequest{Type: r}
+	default:
+		return &EventRequest{Payload: req}
+	}
+}
+
 type EventBase struct {
 	Type       string
 	Attributes map[string]interface{}
@@ -21,6 +57,7 @@ func (e *EventBase) GetAttributes(ctx context.Context) map[string]interface{} {
 	return e.Attributes
 }
 
+// IsTrigger reports whether the incoming event has the type of the event
 func (e *EventBase) IsTrigger(ctx context.Context, req interface{}) bool {
-	return false
+	return e.Type != "" && GetEventRequest(req).Type == e.Type
 }
@@ -0,0 +1,255 @@
+package eca
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Algo2147483647/golang_toolkit/common"
+	"github.com/Algo2147483647/golang_toolkit/rule_engine"
+)
+
+// Types of the built-in events
+const (
+	EventTypeTick         = "tick" // sent periodically by the host so that time based events are checked
+	EventTypeTimer        = "timer"
+	EventTypeCron         = "cron"
+	EventTypeUnitState    = "unit_state"
+	EventTypePayloadMatch = "payload_match"
+)
+
+// TimerEvent is a one-shot event, triggered by the tick events at or after At until it fired, see FiringEvent
+type TimerEvent struct {
+	EventBase
+	At    time.Time
+	Clock common.Clock
+
+	mu    sync.Mutex
+	fired bool
+}
+
+func NewTimerEvent(at time.Time, clock common.Clock) *TimerEvent {
+	return &TimerEvent{
+		EventBase: EventBase{Type: EventTypeTimer},
+		At:        at,
+		Clock:     clock,
+	}
+}
+
+// CloneEvent returns a copy of the timer that has not fired
+func (e *TimerEvent) CloneEvent() Event {
+	result := NewTimerEvent(e.At, e.Clock)
+	result.EventBase = e.EventBase
+	return result
+}
+
+func (e *TimerEvent) IsTrigger(ctx context.Context, req interface{}) bool {
+	if GetEventRequest(req).Type != EventTypeTick {
+		return false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return !e.fired && !common.GetClock(e.Clock).Now().Before(e.At)
+}
+
+// Fire marks the timer as fired, it is not triggered anymore
+func (e *TimerEvent) Fire(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.fired = true
+}
+
+// CronEvent is triggered by the tick events at or after each time of its schedule, until it fired for that
+// time, see FiringEvent. Missed times are not caught up, the next time is computed from the time the event fired.
+type CronEvent struct {
+	EventBase
+	Schedule *common.CronSchedule
+	Clock    common.Clock
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func NewCronEvent(expr string, clock common.Clock) (*CronEvent, error) {
+	schedule, err := common.ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CronEvent{
+		EventBase: EventBase{Type: EventTypeCron},
+		Schedule:  schedule,
+		Clock:     clock,
+		next:      schedule.Next(common.GetClock(clock).Now()),
+	}, nil
+}
+
+// GetNext returns the next time the event is due
+func (e *CronEvent) GetNext() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.next
+}
+
+// CloneEvent returns a copy of the event, due at the next time of the schedule from now
+func (e *CronEvent) CloneEvent() Event {
+	return &CronEvent{
+		EventBase: e.EventBase,
+		Schedule:  e.Schedule,
+		Clock:     e.Clock,
+		next:      e.Schedule.Next(common.GetClock(e.Clock).Now()),
+	}
+}
+
+func (e *CronEvent) IsTrigger(ctx context.Context, req interface{}) bool {
+	if GetEventRequest(req).Type != EventTypeTick {
+		return false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.isDue(common.GetClock(e.Clock).Now())
+}
+
+// Fire moves the event to the next time of its schedule, if it is due
+func (e *CronEvent) Fire(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := common.GetClock(e.Clock).Now()
+	if e.isDue(now) {
+		e.next = e.Schedule.Next(now)
+	}
+}
+
+func (e *CronEvent) isDue(now time.Time) bool {
+	return !e.next.IsZero() && !now.Before(e.next)
+}
+
+// UnitStateLookup returns the state of the unit with the key
+type UnitStateLookup func(ctx context.Context, key string) (string, bool)
+
+// UnitStateEvent is triggered once the unit UnitKey has reached State. The state is looked up with
+// Lookup if set, with the EventRequest.UnitState set by the scheduler otherwise.
+type UnitStateEvent struct {
+	EventBase
+	UnitKey string
+	State   string
+	Lookup  UnitStateLookup
+}
+
+func NewUnitStateEvent(unitKey string, state string, lookup UnitStateLookup) *UnitStateEvent {
+	return &UnitStateEvent{
+		EventBase: EventBase{Type: EventTypeUnitState},
+		UnitKey:   unitKey,
+		State:     state,
+		Lookup:    lookup,
+	}
+}
+
+func (e *UnitStateEvent) IsTrigger(ctx context.Context, req interface{}) bool {
+	lookup := e.Lookup
+	if lookup == nil {
+		lookup = GetEventRequest(req).UnitState
+	}
+	if lookup == nil {
+		return false
+	}
+	state, ok := lookup(ctx, e.UnitKey)
+	return ok && state == e.State
+}
+
+// PayloadMatchEvent is triggered when its rule_engine expression holds on the payload of the incoming
+// event, see ECA.NewEnvironment for the variables. EventType restricts the incoming event type if set.
+type PayloadMatchEvent struct {
+	EventBase
+	EventType string
+	Node      rule_engine.NodeIf
+}
+
+func NewPayloadMatchEvent(eventType string, expr string) (*PayloadMatchEvent, error) {
+	node, err := rule_engine.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile payload match %q: %w", expr, err)
+	}
+
+	return &PayloadMatchEvent{
+		EventBase: EventBase{Type: EventTypePayloadMatch},
+		EventType: eventType,
+		Node:      node,
+	}, nil
+}
+
+func (e *PayloadMatchEvent) IsTrigger(ctx context.Context, req interface{}) bool {
+	request := GetEventRequest(req)
+	if e.EventType != "" && request.Type != e.EventType {
+		return false
+	}
+
+	result, err := rule_engine.Evaluate(e.Node, setPayload(rule_engine.NewEnvironment(), request.Payload))
+	if err != nil || result == nil {
+		return false
+	}
+	match, _ := result.GetValue().(bool)
+	return match
+}
+
+// RegisterBuiltinEvents registers factories for the timer ("at" param, RFC 3339 or time.Time),
+// cron ("expression" param), unit state ("unit_key" and "state" params) and payload match
+// ("expression" and optional "event_type" params) events.
+func (r *Registry) RegisterBuiltinEvents(clock common.Clock) *Registry {
+	r.RegisterEvent(EventTypeTimer, func(def *EventDefinition) (Event, error) {
+		at, ok := def.Params["at"].(time.Time)
+		if !ok {
+			var err error
+			if at, err = time.Parse(time.RFC3339, getStringParam(def.Params, "at")); err != nil {
+				return nil, fmt.Errorf("invalid at param: %w", err)
+			}
+		}
+		event := NewTimerEvent(at, clock)
+		event.Attributes = def.Attributes
+		return event, nil
+	})
+
+	r.RegisterEvent(EventTypeCron, func(def *EventDefinition) (Event, error) {
+		event, err := NewCronEvent(getStringParam(def.Params, "expression"), clock)
+		if err != nil {
+			return nil, err
+		}
+		event.Attributes = def.Attributes
+		return event, nil
+	})
+
+	r.RegisterEvent(EventTypeUnitState, func(def *EventDefinition) (Event, error) {
+		unitKey, state := getStringParam(def.Params, "unit_key"), getStringParam(def.Params, "state")
+		if unitKey == "" || state == "" {
+			return nil, fmt.Errorf("unit_key and state params are required")
+		}
+		event := NewUnitStateEvent(unitKey, state, nil)
+		event.Attributes = def.Attributes
+		return event, nil
+	})
+
+	r.RegisterEvent(EventTypePayloadMatch, func(def *EventDefinition) (Event, error) {
+		event, err := NewPayloadMatchEvent(getStringParam(def.Params, "event_type"), getStringParam(def.Params, "expression"))
+		if err != nil {
+			return nil, err
+		}
+		event.Attributes = def.Attributes
+		return event, nil
+	})
+
+	return r
+}
+
+// getStringParam returns the param if it is a string, "" otherwise
+func getStringParam(params map[string]interface{}, name string) string {
+	value, _ := params[name].(string)
+	return value
+}
@@ -0,0 +1,72 @@
+package eca
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Algo2147483647/golang_toolkit/common"
+)
+
+var tick = &EventRequest{Type: EventTypeTick}
+
+func TestTimerEvent(t *testing.T) {
+	ctx := context.Background()
+	clock := common.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	event := NewTimerEvent(clock.Now().Add(time.Minute), clock)
+
+	if event.IsTrigger(ctx, tick) {
+		t.Error("timer triggered before it was due")
+	}
+
+	clock.Advance(time.Minute)
+	if event.IsTrigger(ctx, &EventRequest{Type: "other"}) {
+		t.Error("timer triggered by an event that is not a tick")
+	}
+	if !event.IsTrigger(ctx, tick) || !event.IsTrigger(ctx, tick) {
+		t.Error("due timer not triggered until it fired")
+	}
+
+	clone := event.CloneEvent().(*TimerEvent)
+	event.Fire(ctx)
+	clock.Advance(time.Hour)
+	if event.IsTrigger(ctx, tick) {
+		t.Error("timer triggered after it fired")
+	}
+	if !clone.IsTrigger(ctx, tick) {
+		t.Error("clone of the timer fired with it")
+	}
+}
+
+func TestCronEvent(t *testing.T) {
+	ctx := context.Background()
+	clock := common.NewFakeClock(time.Date(2024, 1, 1, 10, 7, 0, 0, time.UTC))
+	event, err := NewCronEvent("*/15 * * * *", clock)
+	if err != nil {
+		t.Fatalf("NewCronEvent: %v", err)
+	}
+
+	if next := event.GetNext(); !next.Equal(time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)) {
+		t.Errorf("GetNext = %v, want 10:15", next)
+	}
+	if event.IsTrigger(ctx, tick) {
+		t.Error("cron triggered before it was due")
+	}
+
+	// missed times are not caught up, the event moves past the time it fired
+	clock.Set(time.Date(2024, 1, 1, 10, 40, 0, 0, time.UTC))
+	if !event.IsTrigger(ctx, tick) {
+		t.Error("due cron not triggered")
+	}
+	event.Fire(ctx)
+	if next := event.GetNext(); !next.Equal(time.Date(2024, 1, 1, 10, 45, 0, 0, time.UTC)) {
+		t.Errorf("GetNext after firing = %v, want 10:45", next)
+	}
+	if event.IsTrigger(ctx, tick) {
+		t.Error("cron triggered again before its next time")
+	}
+
+	if _, err := NewCronEvent("not cron", clock); err == nil {
+		t.Error("NewCronEvent with an invalid expression succeeded, want an error")
+	}
+}
@@ -0,0 +1,136 @@
+package eca
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Action step statuses recorded in an ActionOutcome
+const (
+	ActionStatusSucceeded          = "succeeded"
+	ActionStatusFailed             = "failed"
+	ActionStatusCompensated        = "compensated"
+	ActionStatusCompensationFailed = "compensation_failed"
+	ActionStatusNotCompensable     = "not_compensable"
+)
+
+// ActionOutcome records the execution of one action
+type ActionOutcome struct {
+	Index             int       `json:"index"`
+	Status            string    `json:"status"`
+	Attempts          int       `json:"attempts"`
+	Error             string    `json:"error,omitempty"`
+	CompensationError string    `json:"compensation_error,omitempty"`
+	StartedAt         time.Time `json:"started_at"`
+	FinishedAt        time.Time `json:"finished_at"`
+}
+
+// ActionReport records the outcome of every executed action, in execution order
+type ActionReport struct {
+	Outcomes   []*ActionOutcome `json:"outcomes"`
+	RolledBack bool             `json:"rolled_back"`
+}
+
+// ExecuteActions runs the actions in order, retrying each one according to its RetryPolicy.
+// When an action still fails, the actions executed before it are compensated in reverse order
+// (saga style); actions that do not implement Compensator stay applied and are reported as such.
+// The returned error wraps the action error and any compensation error.
+func (eca *ECA) ExecuteActions(ctx context.Context) (*ActionReport, error) {
+	report := &ActionReport{Outcomes: make([]*ActionOutcome, 0, len(eca.Actions))}
+
+	for i, action := range eca.Actions {
+		outcome := &ActionOutcome{Index: i, StartedAt: time.Now()}
+		report.Outcomes = append(report.Outcomes, outcome)
+
+		err := executeWithRetry(ctx, action, outcome)
+		outcome.FinishedAt = time.Now()
+		if err == nil {
+			outcome.Status = ActionStatusSucceeded
+			continue
+		}
+
+		outcome.Status = ActionStatusFailed
+		outcome.Error = err.Error()
+		report.RolledBack = true
+
+		return report, errors.Join(fmt.Errorf("action %d: %w", i, err), eca.compensate(ctx, report.Outcomes[:i]))
+	}
+
+	return report, nil
+}
+
+// compensate undoes the succeeded actions of the outcomes in reverse order
+func (eca *ECA) compensate(ctx context.Context, outcomes []*ActionOutcome) error {
+	var errs []error
+
+	for i := len(outcomes) - 1; i >= 0; i-- {
+		outcome := outcomes[i]
+		compensator, ok := eca.Actions[outcome.Index].(Compensator)
+		if !ok {
+			outcome.Status = ActionStatusNotCompensable
+			continue
+		}
+
+		// Compensation runs even if ctx is canceled, otherwise a canceled run would stay half applied
+		if err := compensator.Compensate(context.WithoutCancel(ctx)); err != nil {
+			outcome.Status = ActionStatusCompensationFailed
+			outcome.CompensationError = err.Error()
+			errs = append(errs, fmt.Errorf("compensate action %d: %w", outcome.Index, err))
+			continue
+		}
+		outcome.Status = ActionStatusCompensated
+	}
+
+	return errors.Join(errs...)
+}
+
+// executeWithRetry executes the action until it succeeds or its retry policy is exhausted
+func executeWithRetry(ctx context.Context, action Action, outcome *ActionOutcome) error {
+	var policy *RetryPolicy
+	if retryable, ok := action.(Retryable); ok {
+		policy = retryable.GetRetryPolicy(ctx)
+	}
+
+	maxAttempts := 1
+	if policy != nil && policy.MaxAttempts > 1 {
+		maxAttempts = policy.MaxAttempts
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			if ctxErr := sleep(ctx, policy.Backoff(attempt-1)); ctxErr != nil {
+				return fmt.Errorf("%w (last attempt: %v)", ctxErr, err)
+			}
+		}
+
+		outcome.Attempts = attempt
+		if err = action.Execute(ctx); err == nil {
+			return nil
+		}
+	}
+
+	if maxAttempts > 1 {
+		return fmt.Errorf("failed after %d attempts: %w", maxAttempts, err)
+	}
+	return err
+}
+
+// sleep waits for d, or returns the error of ctx if it is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
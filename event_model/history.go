@@ -0,0 +1,105 @@
+package event_model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Algo2147483647/golang_toolkit/event_model/eca"
+)
+
+// Kinds of history records
+const (
+	HistoryKindEventReceived      = "event_received"
+	HistoryKindConditionEvaluated = "condition_evaluated"
+	HistoryKindActionExecuted     = "action_executed"
+	HistoryKindStateChanged       = "state_changed"
+)
+
+// HistoryRecord is one entry of the execution history of a unit group instance. Only the fields of
+// its Kind are set.
+type HistoryRecord struct {
+	Sequence    int64     `json:"sequence"`
+	InstanceKey string    `json:"instance_key"`
+	UnitKey     string    `json:"unit_key,omitempty"`
+	Kind        string    `json:"kind"`
+	Time        time.Time `json:"time"`
+
+	// event_received
+	EventTypes []string    `json:"event_types,omitempty"`
+	Payload    interface{} `json:"payload,omitempty"`
+
+	// condition_evaluated
+	Pass       *bool                  `json:"pass,omitempty"`
+	Conditions []*eca.ConditionResult `json:"conditions,omitempty"`
+
+	// action_executed
+	Actions *eca.ActionReport `json:"actions,omitempty"`
+
+	// state_changed
+	FromState string `json:"from_state,omitempty"`
+	ToState   string `json:"to_state,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// HistoryStore is an append-only log of history records, it must be safe for concurrent use
+type HistoryStore interface {
+	// Append stores the record and sets its Sequence
+	Append(ctx context.Context, record *HistoryRecord) error
+	// Query returns the records of the instance in the order they were appended
+	Query(ctx context.Context, instanceKey string) ([]*HistoryRecord, error)
+}
+
+// MemoryHistoryStore keeps history records in memory
+type MemoryHistoryStore struct {
+	mu       sync.RWMutex
+	sequence int64
+	records  map[string][]*HistoryRecord
+}
+
+func NewMemoryHistoryStore() *MemoryHistoryStore {
+	return &MemoryHistoryStore{
+		records: make(map[string][]*HistoryRecord),
+	}
+}
+
+func (s *MemoryHistoryStore) Append(ctx context.Context, record *HistoryRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sequence++
+	record.Sequence = s.sequence
+	s.records[record.InstanceKey] = append(s.records[record.InstanceKey], record)
+	return nil
+}
+
+func (s *MemoryHistoryStore) Query(ctx context.Context, instanceKey string) ([]*HistoryRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]*HistoryRecord(nil), s.records[instanceKey]...), nil
+}
+
+// WriteJSONLines writes the records as JSON Lines, one record per line
+func WriteJSONLines(w io.Writer, records []*HistoryRecord) error {
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to write history record %d: %w", record.Sequence, err)
+		}
+	}
+	return nil
+}
+
+// ExportHistory writes the history of the instance as JSON Lines
+func ExportHistory(ctx context.Context, store HistoryStore, instanceKey string, w io.Writer) error {
+	records, err := store.Query(ctx, instanceKey)
+	if err != nil {
+		return err
+	}
+	return WriteJSONLines(w, records)
+}
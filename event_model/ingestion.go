@@ -0,0 +1,226 @@
+package event_model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Algo2147483647/golang_toolkit/common"
+)
+
+// DefaultDeduplicationWindow is how long an idempotent key is remembered by default
+const DefaultDeduplicationWindow = 24 * time.Hour
+
+// Ingestion outcomes recorded in the audit log
+const (
+	IngestOutcomeProcessed = "processed"
+	IngestOutcomeIgnored   = "ignored" // no unit ran, the key is forgotten
+	IngestOutcomeDuplicate = "duplicate"
+	IngestOutcomeFailed    = "failed"
+)
+
+// IncomingEvent is an event delivered to a unit group instance, possibly more than once
+type IncomingEvent struct {
+	IdempotentKey string      `json:"idempotent_key"`
+	EventTypes    []string    `json:"event_types"`
+	Req           interface{} `json:"req"`
+}
+
+// IngestAuditEntry records what happened to a delivered event
+type IngestAuditEntry struct {
+	DeduplicationKey string     `json:"deduplication_key"`
+	InstanceKey      string     `json:"instance_key"`
+	EventTypes       []string   `json:"event_types"`
+	Outcome          string     `json:"outcome"`
+	Error            string     `json:"error,omitempty"`
+	ReceivedAt       time.Time  `json:"received_at"`
+	FirstSeenAt      time.Time  `json:"first_seen_at"`
+	Result           *RunResult `json:"result,omitempty"`
+}
+
+// Deduplicator remembers idempotent keys for a window of time
+type Deduplicator interface {
+	// MarkSeen atomically records the key, returning false and the first time it was seen
+	// if the key is already known within the window
+	MarkSeen(ctx context.Context, key string, now time.Time, window time.Duration) (first bool, firstSeenAt time.Time, err error)
+	// Forget removes the key, so a failed delivery can be retried
+	Forget(ctx context.Context, key string) error
+}
+
+// IngestAuditLog receives an entry for every ingested event
+type IngestAuditLog interface {
+	Append(ctx context.Context, entry *IngestAuditEntry) error
+}
+
+// BuildIdempotentKey builds the key identifying a delivery to a unit group in a round
+func BuildIdempotentKey(idempotentKey string, unitGroupKey string, round int64) string {
+	return fmt.Sprintf("%s_%s_%d", idempotentKey, unitGroupKey, round)
+}
+
+// EventIngestor runs incoming events on unit group instances at most once per idempotent key
+// within Window, so at-least-once delivery from a queue does not execute actions twice.
+// Replayed events are no-ops that are only recorded in the audit log.
+type EventIngestor struct {
+	Window       time.Duration
+	Deduplicator Deduplicator
+	AuditLog     IngestAuditLog
+	Scheduler    *Scheduler
+	Store        InstanceStore // optional, the instance is checkpointed when set
+	Clock        common.Clock
+}
+
+func NewEventIngestor() *EventIngestor {
+	return &EventIngestor{
+		Window:       DefaultDeduplicationWindow,
+		Deduplicator: NewMemoryDeduplicator(),
+		AuditLog:     NewMemoryIngestAuditLog(),
+		Scheduler:    NewScheduler(),
+		Clock:        common.SystemClock{},
+	}
+}
+
+// Ingest runs the event on the instance unless its idempotent key was already seen for the same
+// instance, unit group and round, so one event fanned out to several instances runs on each of them.
+// An event running no unit is recorded as ignored and its key is forgotten. A failed run forgets the key
+// when a redelivery would run a unit again, e.g. a unit whose actions failed and were rolled back.
+func (i *EventIngestor) Ingest(ctx context.Context, instance *UnitGroupInstance, event *IncomingEvent) (*IngestAuditEntry, error) {
+	if event.IdempotentKey == "" {
+		return nil, fmt.Errorf("event has no idempotent key")
+	}
+
+	unitGroupKey := ""
+	if instance.UnitGroup != nil {
+		unitGroupKey = instance.UnitGroup.Key
+	}
+
+	now := i.Clock.Now()
+	entry := &IngestAuditEntry{
+		DeduplicationKey: BuildIdempotentKey(event.IdempotentKey, unitGroupKey, instance.Round) + "_" + instance.Key,
+		InstanceKey:      instance.Key,
+		EventTypes:       event.EventTypes,
+		ReceivedAt:       now,
+		FirstSeenAt:      now,
+	}
+
+	first, firstSeenAt, err := i.Deduplicator.MarkSeen(ctx, entry.DeduplicationKey, now, i.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	if !first {
+		entry.Outcome = IngestOutcomeDuplicate
+		entry.FirstSeenAt = firstSeenAt
+		return entry, i.AuditLog.Append(ctx, entry)
+	}
+
+	var result *RunResult
+	if i.Store != nil {
+		result, err = instance.Run(ctx, i.Scheduler, i.Store, event.EventTypes, event.Req)
+	} else {
+		scheduler := *i.Scheduler
+		scheduler.InstanceKey = instance.Key
+		result, err = scheduler.Run(ctx, instance.GetUnits(), event.EventTypes, event.Req)
+	}
+	entry.Result = result
+
+	if err != nil {
+		entry.Outcome = IngestOutcomeFailed
+		entry.Error = err.Error()
+
+		if isRetryable(ctx, instance, event) {
+			if forgetErr := i.Deduplicator.Forget(ctx, entry.DeduplicationKey); forgetErr != nil {
+				err = fmt.Errorf("%w (and failed to forget idempotent key: %v)", err, forgetErr)
+			}
+		}
+		if auditErr := i.AuditLog.Append(ctx, entry); auditErr != nil {
+			err = fmt.Errorf("%w (and failed to append audit entry: %v)", err, auditErr)
+		}
+		return entry, err
+	}
+
+	if len(result.Executed) == 0 {
+		entry.Outcome = IngestOutcomeIgnored
+		if err := i.Deduplicator.Forget(ctx, entry.DeduplicationKey); err != nil {
+			return entry, fmt.Errorf("failed to forget idempotent key: %w", err)
+		}
+		return entry, i.AuditLog.Append(ctx, entry)
+	}
+
+	entry.Outcome = IngestOutcomeProcessed
+	return entry, i.AuditLog.Append(ctx, entry)
+}
+
+// isRetryable reports whether a redelivery of the event would run a unit of the instance
+func isRetryable(ctx context.Context, instance *UnitGroupInstance, event *IncomingEvent) bool {
+	units := instance.GetUnits()
+	return len(getTriggeredUnits(ctx, units, event.EventTypes, event.Req, getUnitStateLookup(units))) > 0
+}
+
+// MemoryDeduplicator keeps idempotent keys in memory, expired keys are purged lazily
+type MemoryDeduplicator struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPurge time.Time
+}
+
+func NewMemoryDeduplicator() *MemoryDeduplicator {
+	return &MemoryDeduplicator{
+		seen: make(map[string]time.Time),
+	}
+}
+
+func (d *MemoryDeduplicator) MarkSeen(ctx context.Context, key string, now time.Time, window time.Duration) (bool, time.Time, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now.Sub(d.lastPurge) > window {
+		for k, seenAt := range d.seen {
+			if now.Sub(seenAt) >= window {
+				delete(d.seen, k)
+			}
+		}
+		d.lastPurge = now
+	}
+
+	if seenAt, ok := d.seen[key]; ok && now.Sub(seenAt) < window {
+		return false, seenAt, nil
+	}
+
+	d.seen[key] = now
+	return true, now, nil
+}
+
+func (d *MemoryDeduplicator) Forget(ctx context.Context, key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.seen, key)
+	return nil
+}
+
+// MemoryIngestAuditLog keeps audit entries in memory
+type MemoryIngestAuditLog struct {
+	mu      sync.RWMutex
+	entries []*IngestAuditEntry
+}
+
+func NewMemoryIngestAuditLog() *MemoryIngestAuditLog {
+	return &MemoryIngestAuditLog{}
+}
+
+func (l *MemoryIngestAuditLog) Append(ctx context.Context, entry *IngestAuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// GetEntries returns a copy of the entries in the order they were appended
+func (l *MemoryIngestAuditLog) GetEntries() []*IngestAuditEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return append([]*IngestAuditEntry(nil), l.entries...)
+}
@@ -0,0 +1,73 @@
+package event_model
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIngestRetriesFailedDelivery(t *testing.T) {
+	errAction := errors.New("transient")
+	failing := true
+	group := &UnitGroup{Key: "group", Units: []*Unit{newTestUnit("a", func(ctx context.Context) error {
+		if failing {
+			return errAction
+		}
+		return nil
+	})}}
+	instance := group.NewInstance("instance")
+	ingestor := NewEventIngestor()
+	event := &IncomingEvent{IdempotentKey: "event", EventTypes: []string{testEventType}}
+
+	entry, err := ingestor.Ingest(context.Background(), instance, event)
+	if !errors.Is(err, errAction) || entry.Outcome != IngestOutcomeFailed {
+		t.Fatalf("first delivery: outcome %s, error %v, want %s with %v", entry.Outcome, err, IngestOutcomeFailed, errAction)
+	}
+
+	failing = false
+	entry, err = ingestor.Ingest(context.Background(), instance, event)
+	if err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+	if entry.Outcome != IngestOutcomeProcessed || len(entry.Result.Executed) != 1 {
+		t.Errorf("redelivery: outcome %s executing %v, want %s executing [a]", entry.Outcome, entry.Result.Executed, IngestOutcomeProcessed)
+	}
+
+	entry, err = ingestor.Ingest(context.Background(), instance, event)
+	if err != nil || entry.Outcome != IngestOutcomeDuplicate {
+		t.Errorf("third delivery: outcome %s, error %v, want %s", entry.Outcome, err, IngestOutcomeDuplicate)
+	}
+}
+
+func TestIngestIgnoresEventRunningNoUnit(t *testing.T) {
+	group := &UnitGroup{Key: "group", Units: []*Unit{newTestUnit("a", nil)}}
+	instance := group.NewInstance("instance")
+	ingestor := NewEventIngestor()
+	event := &IncomingEvent{IdempotentKey: "event", EventTypes: []string{"other"}}
+
+	for i := 0; i < 2; i++ {
+		entry, err := ingestor.Ingest(context.Background(), instance, event)
+		if err != nil || entry.Outcome != IngestOutcomeIgnored {
+			t.Errorf("delivery %d: outcome %s, error %v, want %s", i+1, entry.Outcome, err, IngestOutcomeIgnored)
+		}
+	}
+}
+
+func TestIngestKeepsKeyWhenRetryRunsNothing(t *testing.T) {
+	errAfterUnit := errors.New("after unit failed")
+	group := &UnitGroup{Key: "group", Units: []*Unit{newTestUnit("a", nil)}}
+	instance := group.NewInstance("instance")
+	ingestor := NewEventIngestor()
+	ingestor.Scheduler.AfterUnit = func(ctx context.Context, unit *Unit) error { return errAfterUnit }
+	event := &IncomingEvent{IdempotentKey: "event", EventTypes: []string{testEventType}}
+
+	if _, err := ingestor.Ingest(context.Background(), instance, event); !errors.Is(err, errAfterUnit) {
+		t.Fatalf("first delivery error = %v, want %v", err, errAfterUnit)
+	}
+
+	// the unit completed, a redelivery would run nothing, so it stays a duplicate
+	entry, err := ingestor.Ingest(context.Background(), instance, event)
+	if err != nil || entry.Outcome != IngestOutcomeDuplicate {
+		t.Errorf("redelivery: outcome %s, error %v, want %s", entry.Outcome, err, IngestOutcomeDuplicate)
+	}
+}
@@ -0,0 +1,120 @@
+package event_model
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInstanceNotFound = errors.New("unit group instance not found")
+	ErrVersionConflict  = errors.New("unit group instance version conflict")
+)
+
+// InstanceStore persists the state of unit group instances. Save is optimistic: the record's
+// Version must match the stored one (0 for a new instance), and is incremented on success,
+// otherwise ErrVersionConflict is returned and nothing is written.
+type InstanceStore interface {
+	Load(ctx context.Context, key string) (*UnitGroupInstanceRecord, error)
+	Save(ctx context.Context, record *UnitGroupInstanceRecord) error
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, state string) ([]*UnitGroupInstanceRecord, error) // all states when state is empty
+}
+
+// UnitGroupInstanceRecord is the persisted state of a UnitGroupInstance
+type UnitGroupInstanceRecord struct {
+	Key          string                 `json:"key"`
+	UnitGroupKey string                 `json:"unit_group_key"`
+	State        string                 `json:"state"`
+	Round        int64                  `json:"round"`
+	Version      int64                  `json:"version"`
+	Units        []UnitInstanceRecord   `json:"units"`
+	Params       map[string]interface{} `json:"params"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+}
+
+// UnitInstanceRecord is the persisted state of a unit within a group instance
+type UnitInstanceRecord struct {
+	Key       string    `json:"key"`
+	State     string    `json:"state"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// clone copies the record so stores never share memory with their callers
+func (r *UnitGroupInstanceRecord) clone() *UnitGroupInstanceRecord {
+	result := *r
+	result.Units = append([]UnitInstanceRecord(nil), r.Units...)
+	if r.Params != nil {
+		result.Params = make(map[string]interface{}, len(r.Params))
+		for k, v := range r.Params {
+			result.Params[k] = v
+		}
+	}
+	return &result
+}
+
+// MemoryInstanceStore keeps records in memory, for tests and single process deployments
+type MemoryInstanceStore struct {
+	mu      sync.RWMutex
+	records map[string]*UnitGroupInstanceRecord
+}
+
+func NewMemoryInstanceStore() *MemoryInstanceStore {
+	return &MemoryInstanceStore{
+		records: make(map[string]*UnitGroupInstanceRecord),
+	}
+}
+
+func (s *MemoryInstanceStore) Load(ctx context.Context, key string) (*UnitGroupInstanceRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return nil, ErrInstanceNotFound
+	}
+	return record.clone(), nil
+}
+
+func (s *MemoryInstanceStore) Save(ctx context.Context, record *UnitGroupInstanceRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var version int64
+	if stored, ok := s.records[record.Key]; ok {
+		version = stored.Version
+	}
+	if version != record.Version {
+		return ErrVersionConflict
+	}
+
+	record.Version++
+	record.UpdatedAt = time.Now()
+	s.records[record.Key] = record.clone()
+	return nil
+}
+
+func (s *MemoryInstanceStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+func (s *MemoryInstanceStore) List(ctx context.Context, state string) ([]*UnitGroupInstanceRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*UnitGroupInstanceRecord, 0, len(s.records))
+	for _, record := range s.records {
+		if state == "" || record.State == state {
+			result = append(result, record.clone())
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
@@ -0,0 +1,150 @@
+package event_model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const instanceFileExt = ".json"
+
+// FileInstanceStore is an embedded store keeping one JSON file per instance in a directory.
+// Files are replaced atomically (write to a temporary file, then rename), so a crash never
+// leaves a half written record behind. It is safe for concurrent use within one process.
+type FileInstanceStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileInstanceStore creates the directory if needed
+func NewFileInstanceStore(dir string) (*FileInstanceStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create instance directory: %w", err)
+	}
+	return &FileInstanceStore{dir: dir}, nil
+}
+
+func (s *FileInstanceStore) path(key string) string {
+	return filepath.Join(s.dir, url.PathEscape(key)+instanceFileExt)
+}
+
+func (s *FileInstanceStore) read(path string) (*UnitGroupInstanceRecord, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrInstanceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read instance file: %w", err)
+	}
+
+	record := &UnitGroupInstanceRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal instance file %s: %w", path, err)
+	}
+	return record, nil
+}
+
+func (s *FileInstanceStore) Load(ctx context.Context, key string) (*UnitGroupInstanceRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read(s.path(key))
+}
+
+func (s *FileInstanceStore) Save(ctx context.Context, record *UnitGroupInstanceRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(record.Key)
+
+	var version int64
+	stored, err := s.read(path)
+	if err == nil {
+		version = stored.Version
+	} else if !errors.Is(err, ErrInstanceNotFound) {
+		return err
+	}
+	if version != record.Version {
+		return ErrVersionConflict
+	}
+
+	saved := record.clone()
+	saved.Version++
+	saved.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("failed to marshal instance %s: %w", record.Key, err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary instance file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write instance file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync instance file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close instance file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace instance file: %w", err)
+	}
+
+	record.Version = saved.Version
+	record.UpdatedAt = saved.UpdatedAt
+	return nil
+}
+
+func (s *FileInstanceStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete instance file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileInstanceStore) List(ctx context.Context, state string) ([]*UnitGroupInstanceRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read instance directory: %w", err)
+	}
+
+	result := make([]*UnitGroupInstanceRecord, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || filepath.Ext(entry.Name()) != instanceFileExt {
+			continue
+		}
+
+		record, err := s.read(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if state == "" || record.State == state {
+			result = append(result, record)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
@@ -0,0 +1,158 @@
+package event_model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultInstanceTable is the table used by SQLInstanceStore when none is given
+const DefaultInstanceTable = "unit_group_instances"
+
+// SQLInstanceStore keeps records in a SQL table through database/sql. The statements use ?
+// placeholders and ON CONFLICT, as supported by SQLite. The caller opens the database with the
+// driver of their choice, so this package does not depend on one.
+type SQLInstanceStore struct {
+	db    *sql.DB
+	table string
+}
+
+func NewSQLInstanceStore(db *sql.DB, table string) *SQLInstanceStore {
+	if table == "" {
+		table = DefaultInstanceTable
+	}
+	return &SQLInstanceStore{db: db, table: table}
+}
+
+// CreateTable creates the instance table and its state index if they do not exist
+func (s *SQLInstanceStore) CreateTable(ctx context.Context) error {
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	key            TEXT PRIMARY KEY,
+	unit_group_key TEXT NOT NULL,
+	state          TEXT NOT NULL,
+	round          INTEGER NOT NULL,
+	version        INTEGER NOT NULL,
+	data           TEXT NOT NULL,
+	updated_at     TIMESTAMP NOT NULL
+)`, s.table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_state ON %s (state)`, s.table, s.table),
+	}
+
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create instance table: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLInstanceStore) Load(ctx context.Context, key string) (*UnitGroupInstanceRecord, error) {
+	row := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT data, version FROM %s WHERE key = ?`, s.table), key)
+	return scanInstanceRecord(row)
+}
+
+func (s *SQLInstanceStore) Save(ctx context.Context, record *UnitGroupInstanceRecord) error {
+	saved := record.clone()
+	saved.Version++
+	saved.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("failed to marshal instance %s: %w", record.Key, err)
+	}
+
+	var result sql.Result
+	if record.Version == 0 {
+		result, err = s.db.ExecContext(ctx, fmt.Sprintf(
+			`INSERT INTO %s (key, unit_group_key, state, round, version, data, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (key) DO NOTHING`, s.table),
+			saved.Key, saved.UnitGroupKey, saved.State, saved.Round, saved.Version, string(data), saved.UpdatedAt,
+		)
+	} else {
+		result, err = s.db.ExecContext(ctx, fmt.Sprintf(
+			`UPDATE %s SET unit_group_key = ?, state = ?, round = ?, version = ?, data = ?, updated_at = ? WHERE key = ? AND version = ?`, s.table),
+			saved.UnitGroupKey, saved.State, saved.Round, saved.Version, string(data), saved.UpdatedAt, saved.Key, record.Version,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save instance %s: %w", record.Key, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save instance %s: %w", record.Key, err)
+	}
+	if affected == 0 {
+		return ErrVersionConflict
+	}
+
+	record.Version = saved.Version
+	record.UpdatedAt = saved.UpdatedAt
+	return nil
+}
+
+func (s *SQLInstanceStore) Delete(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE key = ?`, s.table), key); err != nil {
+		return fmt.Errorf("failed to delete instance %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *SQLInstanceStore) List(ctx context.Context, state string) ([]*UnitGroupInstanceRecord, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if state == "" {
+		rows, err = s.db.QueryContext(ctx, fmt.Sprintf(`SELECT data, version FROM %s ORDER BY key`, s.table))
+	} else {
+		rows, err = s.db.QueryContext(ctx, fmt.Sprintf(`SELECT data, version FROM %s WHERE state = ? ORDER BY key`, s.table), state)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*UnitGroupInstanceRecord, 0)
+	for rows.Next() {
+		record, err := scanInstanceRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+	return result, nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanInstanceRecord decodes a (data, version) row, the version column is authoritative
+func scanInstanceRecord(row rowScanner) (*UnitGroupInstanceRecord, error) {
+	var (
+		data    string
+		version int64
+	)
+	if err := row.Scan(&data, &version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInstanceNotFound
+		}
+		return nil, fmt.Errorf("failed to scan instance: %w", err)
+	}
+
+	record := &UnitGroupInstanceRecord{}
+	if err := json.Unmarshal([]byte(data), record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal instance: %w", err)
+	}
+	record.Version = version
+	return record, nil
+}
@@ -0,0 +1,82 @@
+package event_model
+
+import "fmt"
+
+// Join policy types, as used in unit definitions
+const (
+	JoinTypeAll  = "all"
+	JoinTypeAny  = "any"
+	JoinTypeNOfM = "n_of_m"
+)
+
+// JoinPolicy decides when a unit with several Pre units may move from UnitStateNotStarted to
+// UnitStateInProgress. It is checked every time one of its Pre units completes.
+type JoinPolicy interface {
+	IsReady(unit *Unit) bool
+}
+
+// JoinPolicyFunc is a custom JoinPolicy predicate
+type JoinPolicyFunc func(unit *Unit) bool
+
+func (f JoinPolicyFunc) IsReady(unit *Unit) bool {
+	return f(unit)
+}
+
+// JoinAll waits for all Pre units to complete, it is the policy of units without one
+type JoinAll struct{}
+
+func (JoinAll) IsReady(unit *Unit) bool {
+	return unit.CountCompletedPre() == len(unit.Pre)
+}
+
+// JoinAny starts the unit as soon as one Pre unit completes
+type JoinAny struct{}
+
+func (JoinAny) IsReady(unit *Unit) bool {
+	return len(unit.Pre) == 0 || unit.CountCompletedPre() > 0
+}
+
+// JoinNOfM starts the unit once N of its Pre units completed
+type JoinNOfM struct {
+	N int
+}
+
+func (j JoinNOfM) IsReady(unit *Unit) bool {
+	return unit.CountCompletedPre() >= min(j.N, len(unit.Pre))
+}
+
+// NewJoinPolicy returns the join policy of the type, n is only used by JoinTypeNOfM
+func NewJoinPolicy(joinType string, n int) (JoinPolicy, error) {
+	switch joinType {
+	case "", JoinTypeAll:
+		return JoinAll{}, nil
+	case JoinTypeAny:
+		return JoinAny{}, nil
+	case JoinTypeNOfM:
+		if n <= 0 {
+			return nil, fmt.Errorf("join %s needs a positive count, got %d", joinType, n)
+		}
+		return JoinNOfM{N: n}, nil
+	default:
+		return nil, fmt.Errorf("unknown join type %s", joinType)
+	}
+}
+
+// CountCompletedPre returns the number of Pre units in UnitStateCompleted
+func (unit *Unit) CountCompletedPre() int {
+	count := 0
+	for _, pre := range unit.Pre {
+		if pre.State == UnitStateCompleted {
+			count++
+		}
+	}
+	return count
+}
+
+// isJoinReady applies the join policy of the unit, JoinAll if it has none
+func (unit *Unit) isJoinReady() bool {
+	if unit.Join == nil {
+		return JoinAll{}.IsReady(unit)
+	}
+	return unit.Join.IsReady(unit)
+}
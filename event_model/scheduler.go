@@ -0,0 +1,313 @@
+package event_model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Algo2147483647/golang_toolkit/common"
+	"github.com/Algo2147483647/golang_toolkit/event_model/eca"
+	"github.com/Algo2147483647/golang_toolkit/math/graph"
+)
+
+// DefaultMaxRounds caps the propagation rounds of a single Run
+const DefaultMaxRounds = 100
+
+// Scheduler propagates events through a graph of units. Every round runs the triggered units,
+// moves their Post units to UnitStateInProgress once their JoinPolicy allows it and re-checks those
+// against the same events, until no unit is triggered anymore (fixed point) or MaxRounds is reached.
+type Scheduler struct {
+	MaxRounds int
+
+	// Workers bounds how many units run at the same time, units are run one at a time when it is 0 or 1
+	Workers int
+
+	// AfterUnit is called after a unit completes and its Post units were activated, e.g. to
+	// checkpoint the state. An error aborts the run.
+	AfterUnit func(ctx context.Context, unit *Unit) error
+
+	// History, when set, receives the received events, condition results with their trace, action
+	// outcomes and state changes of the run, filed under InstanceKey
+	History     HistoryStore
+	InstanceKey string
+	Clock       common.Clock // SystemClock when nil
+}
+
+// RunResult summarizes a Run, the units run by the escalation events of expired units included
+type RunResult struct {
+	Rounds   int                          `json:"rounds"`
+	Executed []string                     `json:"executed"` // keys of the completed units, in execution order
+	Actions  map[string]*eca.ActionReport `json:"actions"`  // action reports by unit key, also of failed units
+}
+
+// merge adds the rounds, executed units and action reports of other, which ran after r
+func (r *RunResult) merge(other *RunResult) {
+	if other == nil {
+		return
+	}
+	r.Rounds += other.Rounds
+	r.Executed = append(r.Executed, other.Executed...)
+	for key, report := range other.Actions {
+		r.addActions(key, report)
+	}
+}
+
+// addActions keeps the action report of the unit
+func (r *RunResult) addActions(unitKey string, report *eca.ActionReport) {
+	if report == nil {
+		return
+	}
+	if r.Actions == nil {
+		r.Actions = make(map[string]*eca.ActionReport)
+	}
+	r.Actions[unitKey] = report
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		MaxRounds: DefaultMaxRounds,
+	}
+}
+
+// Run propagates the events through the units with the default scheduler
+func Run(ctx context.Context, units []*Unit, eventTypes []string, req interface{}) error {
+	_, err := NewScheduler().Run(ctx, units, eventTypes, req)
+	return err
+}
+
+// Run propagates the events through the units. Head units (without Pre) that have not started yet
+// are started first, and units past their deadline expire (see ExpireUnits). The conditions of a
+// triggered unit see req and the attributes of the events that triggered it, see eca.ECA.NewEnvironment.
+func (s *Scheduler) Run(ctx context.Context, units []*Unit, eventTypes []string, req interface{}) (*RunResult, error) {
+	if graph.HasCycle(&UnitGroup{Units: units}) {
+		return nil, fmt.Errorf("unit graph has a cycle")
+	}
+
+	maxRounds := s.MaxRounds
+	if maxRounds <= 0 {
+		maxRounds = DefaultMaxRounds
+	}
+
+	if err := s.record(ctx, &HistoryRecord{Kind: HistoryKindEventReceived, EventTypes: eventTypes, Payload: req}); err != nil {
+		return nil, err
+	}
+
+	for _, unit := range units {
+		if len(unit.Pre) == 0 && unit.isNotStarted() {
+			if err := s.setState(ctx, unit, UnitStateInProgress); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	_, result, err := s.ExpireUnits(ctx, units)
+	if err != nil {
+		return result, err
+	}
+
+	var events *RunResult
+	if s.Workers > 1 {
+		events, err = s.runConcurrent(ctx, units, eventTypes, req, maxRounds)
+	} else {
+		events, err = s.runRounds(ctx, units, eventTypes, req, maxRounds)
+	}
+	result.merge(events)
+	return result, err
+}
+
+// runRounds runs the triggered units one at a time, round by round
+func (s *Scheduler) runRounds(ctx context.Context, units []*Unit, eventTypes []string, req interface{}, maxRounds int) (*RunResult, error) {
+	result := &RunResult{}
+	lookup := getUnitStateLookup(units)
+
+	// 1. Get units by events
+	triggered := getTriggeredUnits(ctx, units, eventTypes, req, lookup)
+
+	// 2. Run units, round by round, until no more unit is triggered
+	for len(triggered) > 0 {
+		if result.Rounds >= maxRounds {
+			return result, fmt.Errorf("unit propagation did not settle after %d rounds", maxRounds)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		result.Rounds++
+		activated := make([]*Unit, 0)
+
+		for _, item := range triggered {
+			unit := item.unit
+			pass, err := s.checkConditions(ctx, unit, item.trigger)
+			if err != nil {
+				return result, fmt.Errorf("unit %s: %w", unit.Key, err)
+			}
+			if !pass {
+				continue
+			}
+
+			if err := s.setState(ctx, unit, UnitStateConditionPass); err != nil {
+				return result, err
+			}
+
+			report, err := s.executeActions(ctx, unit)
+			result.addActions(unit.Key, report)
+			if err != nil {
+				if stateErr := s.retryUnit(ctx, unit); stateErr != nil {
+					return result, stateErr
+				}
+				return result, fmt.Errorf("unit %s: %w", unit.Key, err)
+			}
+			item.trigger.Fire(ctx)
+
+			if err := s.setState(ctx, unit, UnitStateCompleted); err != nil {
+				return result, err
+			}
+			result.Executed = append(result.Executed, unit.Key)
+
+			post, err := s.activatePost(ctx, unit)
+			if err != nil {
+				return result, err
+			}
+			activated = append(activated, post...)
+
+			if s.AfterUnit != nil {
+				if err := s.AfterUnit(ctx, unit); err != nil {
+					return result, fmt.Errorf("unit %s: %w", unit.Key, err)
+				}
+			}
+		}
+
+		triggered = getTriggeredUnits(ctx, activated, eventTypes, req, lookup)
+	}
+
+	return result, nil
+}
+
+// checkConditions checks the conditions of the unit, recording their results when History is set
+func (s *Scheduler) checkConditions(ctx context.Context, unit *Unit, trigger *eca.Trigger) (bool, error) {
+	if s.History == nil {
+		return unit.ConditionPass(ctx, trigger)
+	}
+
+	pass, results, err := unit.EvaluateConditions(ctx, trigger)
+	record := &HistoryRecord{Kind: HistoryKindConditionEvaluated, UnitKey: unit.Key, Pass: &pass, Conditions: results}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if recordErr := s.record(ctx, record); recordErr != nil {
+		return false, recordErr
+	}
+	return pass, err
+}
+
+// executeActions executes the actions of the unit, recording their outcome when History is set
+func (s *Scheduler) executeActions(ctx context.Context, unit *Unit) (*eca.ActionReport, error) {
+	report, err := unit.ExecuteActions(ctx)
+	if s.History == nil {
+		return report, err
+	}
+
+	record := &HistoryRecord{Kind: HistoryKindActionExecuted, UnitKey: unit.Key, Actions: report}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if recordErr := s.record(ctx, record); recordErr != nil {
+		return report, recordErr
+	}
+	return report, err
+}
+
+// retryUnit moves a unit whose actions failed, and were rolled back, back to UnitStateInProgress, so that
+// the next event runs it again. Its deadline is kept.
+func (s *Scheduler) retryUnit(ctx context.Context, unit *Unit) error {
+	startedAt := unit.StartedAt
+	err := s.setState(ctx, unit, UnitStateInProgress)
+	unit.StartedAt = startedAt
+	return err
+}
+
+// activatePost moves the Post units of the unit whose join policy is satisfied to UnitStateInProgress
+// and returns the Post units, so they can be checked against the events
+func (s *Scheduler) activatePost(ctx context.Context, unit *Unit) ([]*Unit, error) {
+	for _, item := range unit.Post {
+		if item.isNotStarted() && item.isJoinReady() {
+			if err := s.setState(ctx, item, UnitStateInProgress); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return unit.Post, nil
+}
+
+// setState changes the state of the unit, recording the change when History is set.
+// Entering UnitStateInProgress starts the deadline of the unit.
+func (s *Scheduler) setState(ctx context.Context, unit *Unit, state string) error {
+	from := unit.State
+	unit.State = state
+	if state == UnitStateInProgress {
+		unit.StartedAt = common.GetClock(s.Clock).Now()
+	}
+	return s.record(ctx, &HistoryRecord{Kind: HistoryKindStateChanged, UnitKey: unit.Key, FromState: from, ToState: state})
+}
+
+// record appends the record to History, if set
+func (s *Scheduler) record(ctx context.Context, record *HistoryRecord) error {
+	if s.History == nil {
+		return nil
+	}
+
+	record.InstanceKey = s.InstanceKey
+	record.Time = common.GetClock(s.Clock).Now()
+	if err := s.History.Append(ctx, record); err != nil {
+		return fmt.Errorf("failed to record history: %w", err)
+	}
+	return nil
+}
+
+// triggeredUnit is a unit triggered by an event, with the events of the unit it triggered
+type triggeredUnit struct {
+	unit    *Unit
+	trigger *eca.Trigger
+}
+
+// getTriggeredUnits returns the distinct in-progress units triggered by any of the events, the events
+// are checked against an eca.EventRequest carrying the event type, req as payload and the lookup.
+// A unit triggered by several events keeps the first one, its conditions are evaluated against it.
+func getTriggeredUnits(ctx context.Context, units []*Unit, eventTypes []string, req interface{}, lookup eca.UnitStateLookup) []*triggeredUnit {
+	result := make([]*triggeredUnit, 0)
+	seen := make(map[*Unit]bool)
+
+	for _, unit := range units {
+		if seen[unit] || unit.State != UnitStateInProgress {
+			continue
+		}
+
+		for _, eventType := range eventTypes {
+			if trigger := unit.Trigger(ctx, &eca.EventRequest{Type: eventType, Payload: req, UnitState: lookup}); trigger != nil {
+				seen[unit] = true
+				result = append(result, &triggeredUnit{unit: unit, trigger: trigger})
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// getUnitStateLookup looks up the state of the units by key
+func getUnitStateLookup(units []*Unit) eca.UnitStateLookup {
+	byKey := make(map[string]*Unit, len(units))
+	for _, unit := range units {
+		byKey[unit.Key] = unit
+	}
+
+	return func(ctx context.Context, key string) (string, bool) {
+		unit, ok := byKey[key]
+		if !ok {
+			return "", false
+		}
+		return unit.State, true
+	}
+}
@@ -0,0 +1,207 @@
+package event_model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Algo2147483647/golang_toolkit/event_model/eca"
+)
+
+// unitOutcome is sent back by a worker once it has run a unit
+type unitOutcome struct {
+	unit    *Unit
+	pass    bool
+	actions *eca.ActionReport
+	err     error
+}
+
+// runConcurrent runs the triggered units on a pool of s.Workers workers. A triggered unit is dispatched
+// once none of its transitive Pre units is still pending (queued or running), so units only run
+// concurrently when they do not depend on each other. Workers check the conditions, move a passing unit to
+// UnitStateConditionPass and execute its actions, moving it back when they fail (see retryUnit); the other state changes, the result and AfterUnit are
+// handled by the calling goroutine, holding a lock against the state changes of the workers.
+// History must be safe for concurrent use, the records of the workers are appended concurrently.
+// The first error cancels the context given to the running units, and Run returns once they are done.
+// Rounds is the length of the longest propagation chain.
+func (s *Scheduler) runConcurrent(ctx context.Context, units []*Unit, eventTypes []string, req interface{}, maxRounds int) (*RunResult, error) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan *triggeredUnit)
+	done := make(chan unitOutcome)
+	var stateMu sync.Mutex // guards the unit states
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				outcome := unitOutcome{unit: item.unit}
+				outcome.pass, outcome.err = s.checkConditions(runCtx, item.unit, item.trigger)
+				if outcome.err == nil && outcome.pass {
+					stateMu.Lock()
+					outcome.err = s.setState(runCtx, item.unit, UnitStateConditionPass)
+					stateMu.Unlock()
+				}
+				if outcome.err == nil && outcome.pass {
+					outcome.actions, outcome.err = s.executeActions(runCtx, item.unit)
+					if outcome.err == nil {
+						item.trigger.Fire(runCtx)
+					} else {
+						stateMu.Lock()
+						if err := s.retryUnit(runCtx, item.unit); err != nil {
+							outcome.err = fmt.Errorf("%w (after %v)", err, outcome.err)
+						}
+						stateMu.Unlock()
+					}
+				}
+				done <- outcome
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	result := &RunResult{}
+	lookup := getUnitStateLookup(units)
+	depth := make(map[*Unit]int)
+	pending := make(map[*Unit]bool)
+	queue := make([]*triggeredUnit, 0)
+
+	enqueue := func(triggered []*triggeredUnit, round int) error {
+		for _, item := range triggered {
+			unit := item.unit
+			if pending[unit] {
+				continue
+			}
+			if round > maxRounds {
+				return fmt.Errorf("unit propagation did not settle after %d rounds", maxRounds)
+			}
+			depth[unit] = round
+			pending[unit] = true
+			queue = append(queue, item)
+		}
+		return nil
+	}
+
+	if err := enqueue(getTriggeredUnits(ctx, units, eventTypes, req, lookup), 1); err != nil {
+		return result, err
+	}
+
+	var firstErr error
+	running := 0
+
+	for len(queue) > 0 || running > 0 {
+		// 1. Dispatch the ready units while a worker is free
+		if firstErr == nil && ctx.Err() == nil {
+			waiting := queue[:0]
+			for _, item := range queue {
+				if running < s.Workers && !hasPendingAncestor(item.unit, pending) {
+					running++
+					jobs <- item
+				} else {
+					waiting = append(waiting, item)
+				}
+			}
+			queue = waiting
+		}
+
+		if running == 0 {
+			break
+		}
+
+		// 2. Collect a finished unit and queue the units it triggers
+		outcome := <-done
+		running--
+		delete(pending, outcome.unit)
+		result.addActions(outcome.unit.Key, outcome.actions)
+
+		if outcome.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unit %s: %w", outcome.unit.Key, outcome.err)
+				cancel()
+			}
+			continue
+		}
+
+		if !outcome.pass {
+			continue
+		}
+
+		// A unit finishing after an error is still recorded, its actions were executed
+		unit := outcome.unit
+		result.Executed = append(result.Executed, unit.Key)
+		result.Rounds = max(result.Rounds, depth[unit])
+
+		stateMu.Lock()
+		activated, err := s.completeUnit(ctx, unit)
+		var triggered []*triggeredUnit
+		if err == nil && firstErr == nil {
+			triggered = getTriggeredUnits(ctx, activated, eventTypes, req, lookup)
+		}
+		stateMu.Unlock()
+
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("unit %s: %w", unit.Key, err)
+			cancel()
+		}
+
+		if firstErr != nil {
+			continue
+		}
+
+		if err := enqueue(triggered, depth[unit]+1); err != nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	if firstErr == nil && len(queue) > 0 {
+		firstErr = ctx.Err()
+	}
+	return result, firstErr
+}
+
+// completeUnit records the completion of a unit run by a worker, activates its Post units and calls AfterUnit
+func (s *Scheduler) completeUnit(ctx context.Context, unit *Unit) ([]*Unit, error) {
+	if err := s.setState(ctx, unit, UnitStateCompleted); err != nil {
+		return nil, err
+	}
+
+	activated, err := s.activatePost(ctx, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.AfterUnit != nil {
+		if err := s.AfterUnit(ctx, unit); err != nil {
+			return nil, err
+		}
+	}
+	return activated, nil
+}
+
+// hasPendingAncestor reports whether a transitive Pre unit of the unit is pending
+func hasPendingAncestor(unit *Unit, pending map[*Unit]bool) bool {
+	visited := make(map[*Unit]bool)
+
+	var visit func(unit *Unit) bool
+	visit = func(unit *Unit) bool {
+		for _, pre := range unit.Pre {
+			if visited[pre] {
+				continue
+			}
+			visited[pre] = true
+			if pending[pre] || visit(pre) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return visit(unit)
+}
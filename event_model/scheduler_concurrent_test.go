@@ -0,0 +1,249 @@
+package event_model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Algo2147483647/golang_toolkit/event_model/eca"
+)
+
+const testEventType = "start"
+
+// testAction runs execute as the action of a unit
+type testAction struct {
+	eca.ActionBase
+	execute func(ctx context.Context) error
+}
+
+func (a *testAction) Execute(ctx context.Context) error {
+	if a.execute == nil {
+		return nil
+	}
+	return a.execute(ctx)
+}
+
+func newTestUnit(key string, execute func(ctx context.Context) error) *Unit {
+	return &Unit{
+		Key: key,
+		ECA: eca.ECA{
+			Events:  []eca.Event{&eca.EventBase{Type: testEventType}},
+			Actions: []eca.Action{&testAction{execute: execute}},
+		},
+	}
+}
+
+func link(pre *Unit, posts ...*Unit) {
+	for _, post := range posts {
+		pre.Post = append(pre.Post, post)
+		post.Pre = append(post.Pre, pre)
+	}
+}
+
+func TestRunConcurrentFanOutFanIn(t *testing.T) {
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	parallel := func(key string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			started <- key
+			select {
+			case <-release:
+				return nil
+			case <-time.After(time.Second):
+				return fmt.Errorf("%s did not run in parallel", key)
+			}
+		}
+	}
+
+	a, b, c, d := newTestUnit("a", nil), newTestUnit("b", parallel("b")), newTestUnit("c", parallel("c")), newTestUnit("d", nil)
+	link(a, b, c)
+	link(b, d)
+	link(c, d)
+	units := []*Unit{a, b, c, d}
+
+	go func() {
+		<-started
+		<-started
+		close(release)
+	}()
+
+	scheduler := NewScheduler()
+	scheduler.Workers = 4
+	result, err := scheduler.Run(context.Background(), units, []string{testEventType}, nil)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if len(result.Executed) != 4 || result.Executed[0] != "a" || result.Executed[3] != "d" {
+		t.Errorf("Executed = %v, want a first and d last", result.Executed)
+	}
+	if result.Rounds != 3 {
+		t.Errorf("Rounds = %d, want 3", result.Rounds)
+	}
+	if len(result.Actions) != 4 {
+		t.Errorf("Actions has %d reports, want 4", len(result.Actions))
+	}
+	for _, unit := range units {
+		if unit.State != UnitStateCompleted {
+			t.Errorf("unit %s is %s, want %s", unit.Key, unit.State, UnitStateCompleted)
+		}
+	}
+}
+
+func TestRunConcurrentCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	a := newTestUnit("a", nil)
+	b := newTestUnit("b", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c := newTestUnit("c", nil)
+	link(a, b)
+	link(b, c)
+
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	scheduler := NewScheduler()
+	scheduler.Workers = 2
+	result, err := scheduler.Run(ctx, []*Unit{a, b, c}, []string{testEventType}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run error = %v, want %v", err, context.Canceled)
+	}
+
+	if len(result.Executed) != 1 || result.Executed[0] != "a" {
+		t.Errorf("Executed = %v, want [a]", result.Executed)
+	}
+	if b.State != UnitStateInProgress {
+		t.Errorf("unit b is %s, want %s", b.State, UnitStateInProgress)
+	}
+	if !c.isNotStarted() {
+		t.Errorf("unit c is %s, want it not started", c.State)
+	}
+}
+
+func TestRunConcurrentError(t *testing.T) {
+	errAction := errors.New("action failed")
+	failing := true
+
+	a := newTestUnit("a", nil)
+	b := newTestUnit("b", func(ctx context.Context) error {
+		if failing {
+			return errAction
+		}
+		return nil
+	})
+	c := newTestUnit("c", nil)
+	d := newTestUnit("d", nil)
+	link(a, b, c)
+	link(b, d)
+	units := []*Unit{a, b, c, d}
+
+	scheduler := NewScheduler()
+	scheduler.Workers = 2
+	result, err := scheduler.Run(context.Background(), units, []string{testEventType}, nil)
+	if !errors.Is(err, errAction) {
+		t.Fatalf("Run error = %v, want %v", err, errAction)
+	}
+
+	if b.State != UnitStateInProgress {
+		t.Errorf("unit b is %s, want %s", b.State, UnitStateInProgress)
+	}
+	if !d.isNotStarted() {
+		t.Errorf("unit d is %s, want it not started", d.State)
+	}
+	if report := result.Actions["b"]; report == nil || len(report.Outcomes) != 1 {
+		t.Errorf("Actions[b] = %v, want the failed action", report)
+	}
+
+	// the event is delivered again once the action recovered
+	failing = false
+	result, err = scheduler.Run(context.Background(), units, []string{testEventType}, nil)
+	if err != nil {
+		t.Fatalf("retried Run: %v", err)
+	}
+	if len(result.Executed) != 2 || result.Executed[0] != "b" || result.Executed[1] != "d" {
+		t.Errorf("retried Run executed %v, want [b d]", result.Executed)
+	}
+	for _, unit := range units {
+		if unit.State != UnitStateCompleted {
+			t.Errorf("unit %s is %s, want %s", unit.Key, unit.State, UnitStateCompleted)
+		}
+	}
+}
+
+func TestRunConcurrentHistoryAndAfterUnit(t *testing.T) {
+	const width = 8
+
+	head, tail := newTestUnit("head", nil), newTestUnit("tail", nil)
+	units := []*Unit{head}
+	for i := 0; i < width; i++ {
+		unit := newTestUnit(fmt.Sprintf("u%d", i), nil)
+		link(head, unit)
+		link(unit, tail)
+		units = append(units, unit)
+	}
+	units = append(units, tail)
+
+	var mu sync.Mutex
+	afterUnit := make([]string, 0)
+	history := NewMemoryHistoryStore()
+
+	scheduler := NewScheduler()
+	scheduler.Workers = 4
+	scheduler.History = history
+	scheduler.InstanceKey = "instance"
+	scheduler.AfterUnit = func(ctx context.Context, unit *Unit) error {
+		completed := 0
+		for _, item := range units {
+			if item.State == UnitStateCompleted {
+				completed++
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		afterUnit = append(afterUnit, unit.Key)
+		if completed != len(afterUnit) {
+			return fmt.Errorf("%d units completed after %d units", completed, len(afterUnit))
+		}
+		return nil
+	}
+
+	if _, err := scheduler.Run(context.Background(), units, []string{testEventType}, nil); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if len(afterUnit) != len(units) || afterUnit[len(afterUnit)-1] != "tail" {
+		t.Errorf("AfterUnit called for %v, want every unit and tail last", afterUnit)
+	}
+
+	records, err := history.Query(context.Background(), "instance")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, record := range records {
+		counts[record.Kind]++
+		if (record.Pass != nil) != (record.Kind == HistoryKindConditionEvaluated) {
+			t.Errorf("%s record has pass %v, want it set on condition records only", record.Kind, record.Pass)
+		}
+	}
+	if counts[HistoryKindConditionEvaluated] != len(units) || counts[HistoryKindActionExecuted] != len(units) {
+		t.Errorf("history counts = %v, want a condition and an action record per unit", counts)
+	}
+	// in progress, condition passed and completed for every unit
+	if counts[HistoryKindStateChanged] != 3*len(units) {
+		t.Errorf("history has %d state changes, want %d", counts[HistoryKindStateChanged], 3*len(units))
+	}
+}
@@ -0,0 +1,50 @@
+package event_model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Algo2147483647/golang_toolkit/common"
+	"github.com/Algo2147483647/golang_toolkit/event_model/eca"
+)
+
+func TestRunRetryAfterActionError(t *testing.T) {
+	errAction := errors.New("transient")
+	clock := common.NewFakeClock(time.Unix(0, 0))
+	calls := 0
+
+	unit := newTestUnit("a", func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errAction
+		}
+		return nil
+	})
+	unit.Events = []eca.Event{eca.NewTimerEvent(time.Unix(0, 0), clock)}
+	unit.Timeout = time.Minute
+	units := []*Unit{unit}
+
+	scheduler := NewScheduler()
+	scheduler.Clock = clock
+	if _, err := scheduler.Run(context.Background(), units, []string{eca.EventTypeTick}, nil); !errors.Is(err, errAction) {
+		t.Fatalf("Run error = %v, want %v", err, errAction)
+	}
+	if unit.State != UnitStateInProgress {
+		t.Fatalf("unit is %s after the failure, want %s", unit.State, UnitStateInProgress)
+	}
+	startedAt := unit.StartedAt
+
+	clock.Advance(time.Second)
+	result, err := scheduler.Run(context.Background(), units, []string{eca.EventTypeTick}, nil)
+	if err != nil {
+		t.Fatalf("retried Run: %v", err)
+	}
+	if len(result.Executed) != 1 || unit.State != UnitStateCompleted {
+		t.Errorf("retried Run executed %v leaving the unit %s, want it completed", result.Executed, unit.State)
+	}
+	if !unit.StartedAt.Equal(startedAt) {
+		t.Errorf("StartedAt moved from %v to %v, the retry must keep the deadline", startedAt, unit.StartedAt)
+	}
+}
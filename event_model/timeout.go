@@ -0,0 +1,82 @@
+package event_model
+
+import (
+	"context"
+	"time"
+
+	"github.com/Algo2147483647/golang_toolkit/common"
+)
+
+// EventTypeUnitTimeout is the escalation event of units without EscalationEvent
+const EventTypeUnitTimeout = "unit_timeout"
+
+// UnitTimeout is the payload of an escalation event
+type UnitTimeout struct {
+	UnitKey   string    `json:"unit_key"`
+	StartedAt time.Time `json:"started_at"`
+	Deadline  time.Time `json:"deadline"`
+}
+
+// GetDeadline returns when the unit times out, ok is false if it has no deadline
+func (unit *Unit) GetDeadline() (deadline time.Time, ok bool) {
+	if unit.Timeout <= 0 || unit.StartedAt.IsZero() {
+		return time.Time{}, false
+	}
+	return unit.StartedAt.Add(unit.Timeout), true
+}
+
+func (unit *Unit) getEscalationEvent() string {
+	if unit.EscalationEvent == "" {
+		return EventTypeUnitTimeout
+	}
+	return unit.EscalationEvent
+}
+
+// ExpireUnits moves the in-progress units whose deadline passed to UnitStateTimedOut and starts their
+// TimeoutPost units, bypassing their join policy. Then the escalation event of every expired unit is
+// propagated through the units, with a UnitTimeout payload. Run calls it before handling its events,
+// so running the scheduler periodically (e.g. with eca.EventTypeTick) enforces the deadlines.
+// The expired units are returned with the result of the escalations.
+func (s *Scheduler) ExpireUnits(ctx context.Context, units []*Unit) ([]*Unit, *RunResult, error) {
+	now := common.GetClock(s.Clock).Now()
+	expired := make([]*Unit, 0)
+	result := &RunResult{}
+
+	for _, unit := range units {
+		if unit.State != UnitStateInProgress {
+			continue
+		}
+		if deadline, ok := unit.GetDeadline(); !ok || now.Before(deadline) {
+			continue
+		}
+
+		if err := s.setState(ctx, unit, UnitStateTimedOut); err != nil {
+			return expired, result, err
+		}
+		for _, item := range unit.TimeoutPost {
+			if item.isNotStarted() {
+				if err := s.setState(ctx, item, UnitStateInProgress); err != nil {
+					return expired, result, err
+				}
+			}
+		}
+		if s.AfterUnit != nil {
+			if err := s.AfterUnit(ctx, unit); err != nil {
+				return expired, result, err
+			}
+		}
+		expired = append(expired, unit)
+	}
+
+	for _, unit := range expired {
+		deadline, _ := unit.GetDeadline()
+		escalation := &UnitTimeout{UnitKey: unit.Key, StartedAt: unit.StartedAt, Deadline: deadline}
+		escalated, err := s.Run(ctx, units, []string{unit.getEscalationEvent()}, escalation)
+		result.merge(escalated)
+		if err != nil {
+			return expired, result, err
+		}
+	}
+
+	return expired, result, nil
+}
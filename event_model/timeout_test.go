@@ -0,0 +1,46 @@
+package event_model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Algo2147483647/golang_toolkit/common"
+	"github.com/Algo2147483647/golang_toolkit/event_model/eca"
+)
+
+func TestRunResultIncludesEscalations(t *testing.T) {
+	clock := common.NewFakeClock(time.Unix(0, 0))
+	a := newTestUnit("a", nil)
+	a.Events = []eca.Event{&eca.EventBase{Type: "never"}}
+	a.Timeout = time.Minute
+	b := newTestUnit("b", nil)
+	b.Events = []eca.Event{&eca.EventBase{Type: EventTypeUnitTimeout}}
+	a.TimeoutPost = []*Unit{b}
+	units := []*Unit{a, b}
+
+	for _, workers := range []int{0, 2} {
+		a.State, b.State = "", ""
+		clock.Set(time.Unix(0, 0))
+
+		scheduler := NewScheduler()
+		scheduler.Clock = clock
+		scheduler.Workers = workers
+		if _, err := scheduler.Run(context.Background(), units, []string{eca.EventTypeTick}, nil); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+
+		clock.Advance(2 * time.Minute)
+		result, err := scheduler.Run(context.Background(), units, []string{eca.EventTypeTick}, nil)
+		if err != nil {
+			t.Fatalf("Run after the deadline: %v", err)
+		}
+
+		if a.State != UnitStateTimedOut || b.State != UnitStateCompleted {
+			t.Errorf("workers %d: units a %s and b %s, want timed out and completed", workers, a.State, b.State)
+		}
+		if len(result.Executed) != 1 || result.Executed[0] != "b" || result.Actions["b"] == nil {
+			t.Errorf("workers %d: result executed %v with actions %v, want the escalation of b", workers, result.Executed, result.Actions)
+		}
+	}
+}
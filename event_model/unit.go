@@ -1,7 +1,8 @@
 package event_model
 
 import (
-	"context"
+	"time"
+
 	"github.com/Algo2147483647/golang_toolkit/event_model/eca"
 	"github.com/Algo2147483647/golang_toolkit/math/graph"
 )
@@ -13,22 +14,49 @@ type Unit struct {
 	Pre    []*Unit                `json:"pre"`
 	Post   []*Unit                `json:"post"`
 	Params map[string]interface{} `json:"params"`
+	Join   JoinPolicy             `json:"-"` // JoinAll when nil
+
+	// Timeout is how long the unit may stay in UnitStateInProgress, 0 means forever. Once it passed, the
+	// unit moves to UnitStateTimedOut, the TimeoutPost units start and EscalationEvent is propagated.
+	Timeout         time.Duration `json:"timeout"`
+	TimeoutPost     []*Unit       `json:"timeout_post"`
+	EscalationEvent string        `json:"escalation_event"` // EventTypeUnitTimeout when empty
+	StartedAt       time.Time     `json:"started_at"`       // when the unit moved to UnitStateInProgress
 }
 
 func UnitsToNodes(units []*Unit) []graph.Node {
 	result := make([]graph.Node, len(units))
 	for i, item := range units {
-		units[i] = item
+		result[i] = item
 	}
 	return result
 }
 
+// UnitEdge is the edge from a unit to one of its Post or TimeoutPost units
+type UnitEdge struct {
+	From    *Unit
+	To      *Unit
+	Timeout bool // the edge leads to a TimeoutPost unit
+}
+
+func (e *UnitEdge) GetNodes() (graph.Node, graph.Node) {
+	return e.From, e.To
+}
+
 func (unit *Unit) GetPreNodes() []graph.Node {
 	return UnitsToNodes(unit.Pre)
 }
 
+// GetPostNodes returns the Post units followed by the TimeoutPost units
 func (unit *Unit) GetPostNodes() []graph.Node {
-	return UnitsToNodes(unit.Post)
+	return UnitsToNodes(unit.getSuccessors())
+}
+
+func (unit *Unit) getSuccessors() []*Unit {
+	if len(unit.TimeoutPost) == 0 {
+		return unit.Post
+	}
+	return append(append([]*Unit{}, unit.Post...), unit.TimeoutPost...)
 }
 
 type UnitInstance struct {
@@ -42,48 +70,10 @@ const (
 	UnitStateInProgress    = "in_progress"
 	UnitStateConditionPass = "condition_passed"
 	UnitStateCompleted     = "completed"
+	UnitStateTimedOut      = "timed_out"
 )
 
-func Run(ctx context.Context, units []*Unit, eventTypes []string, req interface{}) error {
-	// 1. Get units by events
-	newUnits := make([]*Unit, 0)
-
-	for _, unit := range units {
-		for _, eventType := range eventTypes {
-			if unit.State == UnitStateInProgress && unit.EventTrigger(ctx, eventType) {
-				newUnits = append(newUnits, unit)
-			}
-		}
-	}
-
-	units = newUnits
-
-	// 2. Run units
-	for _, unit := range units {
-		if !unit.ConditionPass(ctx, req) {
-			continue
-		}
-
-		unit.State = UnitStateConditionPass
-
-		if err := unit.ActionExecute(ctx); err != nil {
-			return err
-		}
-
-		unit.State = UnitStateCompleted
-
-		for _, item := range unit.Post {
-			if item.State == UnitStateNotStarted {
-				item.State = UnitStateInProgress
-			}
-
-			for _, eventType := range eventTypes {
-				if item.State == UnitStateInProgress && item.EventTrigger(ctx, eventType) {
-					newUnits = append(newUnits, item)
-				}
-			}
-		}
-	}
-
-	return nil
+// isNotStarted reports whether the unit has not started, a unit without state has not started either
+func (unit *Unit) isNotStarted() bool {
+	return unit.State == "" || unit.State == UnitStateNotStarted
 }
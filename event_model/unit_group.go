@@ -1,6 +1,10 @@
 package event_model
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
 
 type UnitGroup struct {
 	Key    string
@@ -12,6 +16,7 @@ type UnitGroupInstance struct {
 	Key       string
 	State     string
 	Round     int64
+	Version   int64   `json:"version"`
 	Units     []*Unit `json:"units"`
 	UnitGroup *UnitGroup
 	Params    map[string]interface{} `json:"params"`
@@ -23,6 +28,217 @@ const (
 	UnitGroupStateCompleted  = "completed"
 )
 
+// NewInstance instantiates the group: the units are deep copied with their Pre, Post and TimeoutPost
+// links, so every instance has its own unit states and many instances of one group can run concurrently.
+// Conditions and actions are shared with the group, see eca.ECA.Clone.
+func (g *UnitGroup) NewInstance(key string) *UnitGroupInstance {
+	return &UnitGroupInstance{
+		Key:       key,
+		State:     UnitGroupStateNotStarted,
+		Units:     cloneUnits(g.Units),
+		UnitGroup: g,
+		Params:    copyParams(g.Params),
+	}
+}
+
+// NextRound instantiates the group again for the next round, with the params of this instance.
+// The caller sets the key of the new instance, e.g. with SetIdempotentKey.
+func (f *UnitGroupInstance) NextRound() *UnitGroupInstance {
+	result := f.UnitGroup.NewInstance("")
+	result.Round = f.Round + 1
+	result.Params = copyParams(f.Params)
+	return result
+}
+
+// cloneUnits copies the units in their initial state, links between them are remapped to the copies
+func cloneUnits(units []*Unit) []*Unit {
+	copies := make(map[*Unit]*Unit, len(units))
+	for _, unit := range units {
+		copies[unit] = &Unit{
+			ECA:             unit.ECA.Clone(),
+			Key:             unit.Key,
+			State:           UnitStateNotStarted,
+			Params:          copyParams(unit.Params),
+			Join:            unit.Join,
+			Timeout:         unit.Timeout,
+			EscalationEvent: unit.EscalationEvent,
+		}
+	}
+
+	remap := func(links []*Unit) []*Unit {
+		if links == nil {
+			return nil
+		}
+		result := make([]*Unit, 0, len(links))
+		for _, link := range links {
+			if copied, ok := copies[link]; ok {
+				result = append(result, copied)
+			}
+		}
+		return result
+	}
+
+	result := make([]*Unit, 0, len(units))
+	for _, unit := range units {
+		copied := copies[unit]
+		copied.Pre = remap(unit.Pre)
+		copied.Post = remap(unit.Post)
+		copied.TimeoutPost = remap(unit.TimeoutPost)
+		result = append(result, copied)
+	}
+	return result
+}
+
+func copyParams(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		result[k] = v
+	}
+	return result
+}
+
 func (f *UnitGroupInstance) SetIdempotentKey(idempotentKey string) {
-	f.Key = fmt.Sprintf("%s_%s_%d", idempotentKey, f.UnitGroup.Key, f.Round)
+	f.Key = BuildIdempotentKey(idempotentKey, f.UnitGroup.Key, f.Round)
+}
+
+// GetUnits returns the units of the instance. An instance not created with NewInstance gets its own
+// copy of the units of its group on the first call, the units of the group are never run.
+func (f *UnitGroupInstance) GetUnits() []*Unit {
+	if f.Units == nil && f.UnitGroup != nil {
+		f.Units = cloneUnits(f.UnitGroup.Units)
+	}
+	return f.Units
+}
+
+// GetUnitState returns the state of the unit with the key, it can be used as an eca.UnitStateLookup
+func (f *UnitGroupInstance) GetUnitState(ctx context.Context, key string) (string, bool) {
+	for _, unit := range f.GetUnits() {
+		if unit.Key == key {
+			return unit.State, true
+		}
+	}
+	return "", false
+}
+
+// ToRecord captures the persistent state of the instance
+func (f *UnitGroupInstance) ToRecord() *UnitGroupInstanceRecord {
+	record := &UnitGroupInstanceRecord{
+		Key:     f.Key,
+		State:   f.State,
+		Round:   f.Round,
+		Version: f.Version,
+		Params:  f.Params,
+	}
+
+	if f.UnitGroup != nil {
+		record.UnitGroupKey = f.UnitGroup.Key
+	}
+
+	for _, unit := range f.GetUnits() {
+		record.Units = append(record.Units, UnitInstanceRecord{Key: unit.Key, State: unit.State, StartedAt: unit.StartedAt})
+	}
+
+	return record
+}
+
+// ApplyRecord restores the state saved by ToRecord, units are matched by key
+func (f *UnitGroupInstance) ApplyRecord(record *UnitGroupInstanceRecord) {
+	f.Key = record.Key
+	f.State = record.State
+	f.Round = record.Round
+	f.Version = record.Version
+	f.Params = record.Params
+
+	units := make(map[string]UnitInstanceRecord, len(record.Units))
+	for _, unit := range record.Units {
+		units[unit.Key] = unit
+	}
+
+	for _, unit := range f.GetUnits() {
+		if saved, ok := units[unit.Key]; ok {
+			unit.State = saved.State
+			unit.StartedAt = saved.StartedAt
+		}
+	}
+}
+
+// Save writes the state of the instance to the store, with optimistic versioning
+func (f *UnitGroupInstance) Save(ctx context.Context, store InstanceStore) error {
+	record := f.ToRecord()
+	if err := store.Save(ctx, record); err != nil {
+		return err
+	}
+
+	f.Version = record.Version
+	return nil
+}
+
+// Run propagates the events through the units of the instance and checkpoints the state to the
+// store after every completed unit. A run interrupted by a crash resumes from the last checkpoint
+// when the instance is loaded with ResumeUnitGroupInstance and run again.
+func (f *UnitGroupInstance) Run(ctx context.Context, scheduler *Scheduler, store InstanceStore, eventTypes []string, req interface{}) (*RunResult, error) {
+	if scheduler == nil {
+		scheduler = NewScheduler()
+	}
+
+	runScheduler := *scheduler
+	runScheduler.InstanceKey = f.Key
+	runScheduler.AfterUnit = func(ctx context.Context, unit *Unit) error {
+		if scheduler.AfterUnit != nil {
+			if err := scheduler.AfterUnit(ctx, unit); err != nil {
+				return err
+			}
+		}
+		return f.Save(ctx, store)
+	}
+
+	if f.State == "" || f.State == UnitGroupStateNotStarted {
+		f.State = UnitGroupStateInProgress
+	}
+
+	result, err := runScheduler.Run(ctx, f.GetUnits(), eventTypes, req)
+	if err != nil {
+		return result, err
+	}
+
+	if f.isCompleted() {
+		f.State = UnitGroupStateCompleted
+	}
+
+	return result, f.Save(ctx, store)
+}
+
+// isCompleted reports whether no unit is in progress anymore: every unit completed, timed out, or can no
+// longer start because the branch leading to it was not taken
+func (f *UnitGroupInstance) isCompleted() bool {
+	for _, unit := range f.GetUnits() {
+		if unit.State == UnitStateInProgress || unit.State == UnitStateConditionPass {
+			return false
+		}
+	}
+	return true
+}
+
+// ResumeUnitGroupInstance instantiates the group and loads the instance state from the store onto it.
+// A new instance is returned when the store has no record for the key.
+func ResumeUnitGroupInstance(ctx context.Context, store InstanceStore, unitGroup *UnitGroup, key string) (*UnitGroupInstance, error) {
+	instance := unitGroup.NewInstance(key)
+
+	record, err := store.Load(ctx, key)
+	if errors.Is(err, ErrInstanceNotFound) {
+		return instance, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if record.UnitGroupKey != "" && record.UnitGroupKey != unitGroup.Key {
+		return nil, fmt.Errorf("instance %s belongs to unit group %s, not %s", key, record.UnitGroupKey, unitGroup.Key)
+	}
+
+	instance.ApplyRecord(record)
+	return instance, nil
 }
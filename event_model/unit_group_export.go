@@ -0,0 +1,90 @@
+package event_model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Algo2147483647/golang_toolkit/math/graph"
+)
+
+// UnitStateColors are the fill colors of the unit states in exported graphs
+var UnitStateColors = map[string]string{
+	UnitStateNotStarted:    "#e5e7eb",
+	UnitStateInProgress:    "#bfdbfe",
+	UnitStateConditionPass: "#fde68a",
+	UnitStateCompleted:     "#bbf7d0",
+	UnitStateTimedOut:      "#fecaca",
+}
+
+// ToDOT renders the unit graph in the Graphviz DOT language, see GetExportOptions
+func (g *UnitGroup) ToDOT() string {
+	return graph.ToDOT(g, g.GetExportOptions())
+}
+
+// ToMermaid renders the unit graph as a Mermaid flowchart, see GetExportOptions
+func (g *UnitGroup) ToMermaid() string {
+	return graph.ToMermaid(g, g.GetExportOptions())
+}
+
+// GetExportOptions labels every unit with its key, its state and its condition expressions, and colors
+// it by state with UnitStateColors. Edges to TimeoutPost units are labelled "timeout".
+func (g *UnitGroup) GetExportOptions() *graph.ExportOptions {
+	return newUnitExportOptions(g.Key)
+}
+
+// ToDOT renders the units of the instance in their current state in the Graphviz DOT language
+func (f *UnitGroupInstance) ToDOT() string {
+	return graph.ToDOT(f, f.GetExportOptions())
+}
+
+// ToMermaid renders the units of the instance in their current state as a Mermaid flowchart
+func (f *UnitGroupInstance) ToMermaid() string {
+	return graph.ToMermaid(f, f.GetExportOptions())
+}
+
+// GetExportOptions renders the units like UnitGroup.GetExportOptions, the graph is named after the instance
+func (f *UnitGroupInstance) GetExportOptions() *graph.ExportOptions {
+	return newUnitExportOptions(f.Key)
+}
+
+func newUnitExportOptions(name string) *graph.ExportOptions {
+	return &graph.ExportOptions{
+		Name: name,
+		NodeLabel: func(node graph.Node) string {
+			return getUnitLabel(node.(*Unit))
+		},
+		EdgeLabel: func(edge graph.Edge) string {
+			if unitEdge, ok := edge.(*UnitEdge); ok && unitEdge.Timeout {
+				return "timeout"
+			}
+			return ""
+		},
+		NodeColor: func(node graph.Node) string {
+			unit := node.(*Unit)
+			if unit.isNotStarted() {
+				return UnitStateColors[UnitStateNotStarted]
+			}
+			return UnitStateColors[unit.State]
+		},
+	}
+}
+
+func getUnitLabel(unit *Unit) string {
+	lines := []string{unit.Key}
+
+	state := unit.State
+	if unit.isNotStarted() {
+		state = UnitStateNotStarted
+	}
+	lines = append(lines, "["+state+"]")
+
+	for _, condition := range unit.Conditions {
+		if stringer, ok := condition.(fmt.Stringer); ok {
+			lines = append(lines, stringer.String())
+		} else {
+			lines = append(lines, fmt.Sprintf("%T", condition))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
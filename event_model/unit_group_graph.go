@@ -0,0 +1,124 @@
+package event_model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Algo2147483647/golang_toolkit/math/graph"
+)
+
+func (g *UnitGroup) GetNodes() []graph.Node {
+	return UnitsToNodes(g.Units)
+}
+
+// GetEdges returns an edge from every unit to each of its Post and TimeoutPost units
+func (g *UnitGroup) GetEdges() []graph.Edge {
+	return getUnitEdges(g.Units)
+}
+
+func (f *UnitGroupInstance) GetNodes() []graph.Node {
+	return UnitsToNodes(f.GetUnits())
+}
+
+// GetEdges returns an edge from every unit of the instance to each of its Post and TimeoutPost units
+func (f *UnitGroupInstance) GetEdges() []graph.Edge {
+	return getUnitEdges(f.GetUnits())
+}
+
+func getUnitEdges(units []*Unit) []graph.Edge {
+	result := make([]graph.Edge, 0)
+	for _, unit := range units {
+		for _, post := range unit.Post {
+			result = append(result, &UnitEdge{From: unit, To: post})
+		}
+		for _, post := range unit.TimeoutPost {
+			result = append(result, &UnitEdge{From: unit, To: post, Timeout: true})
+		}
+	}
+	return result
+}
+
+// Validate checks the unit graph of the group: units must have distinct keys, Pre and Post (or
+// TimeoutPost) must reference units of the group and mirror each other, the graph must have no cycle,
+// and every unit must be reachable from a head unit (without Pre). All problems found are returned together.
+func (g *UnitGroup) Validate() error {
+	errs := make([]error, 0)
+
+	members := make(map[*Unit]bool, len(g.Units))
+	keys := make(map[string]bool, len(g.Units))
+	for _, unit := range g.Units {
+		members[unit] = true
+		if keys[unit.Key] {
+			errs = append(errs, fmt.Errorf("duplicate unit %s", unit.Key))
+		}
+		keys[unit.Key] = true
+	}
+
+	for _, unit := range g.Units {
+		for _, post := range unit.getSuccessors() {
+			if !members[post] {
+				errs = append(errs, fmt.Errorf("unit %s has post unit %s outside of the group", unit.Key, post.Key))
+			} else if !containsUnit(post.Pre, unit) {
+				errs = append(errs, fmt.Errorf("unit %s has post unit %s, which lacks it as pre unit", unit.Key, post.Key))
+			}
+		}
+		for _, pre := range unit.Pre {
+			if !members[pre] {
+				errs = append(errs, fmt.Errorf("unit %s has pre unit %s outside of the group", unit.Key, pre.Key))
+			} else if !containsUnit(pre.getSuccessors(), unit) {
+				errs = append(errs, fmt.Errorf("unit %s has pre unit %s, which lacks it as post unit", unit.Key, pre.Key))
+			}
+		}
+	}
+
+	if graph.HasCycle(g) {
+		errs = append(errs, fmt.Errorf("unit graph has a cycle"))
+	}
+
+	for _, key := range g.GetUnreachableUnits() {
+		errs = append(errs, fmt.Errorf("unit %s is unreachable from the head units", key))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("unit group %s is invalid: %w", g.Key, errors.Join(errs...))
+	}
+	return nil
+}
+
+// GetUnreachableUnits returns the keys of the units that cannot be reached from a head unit along
+// Post and TimeoutPost
+func (g *UnitGroup) GetUnreachableUnits() []string {
+	reached := make(map[graph.Node]bool)
+	for _, head := range graph.GetHeadTasks(g) {
+		for _, node := range graph.BFS(head) {
+			reached[node] = true
+		}
+	}
+
+	result := make([]string, 0)
+	for _, unit := range g.Units {
+		if !reached[unit] {
+			result = append(result, unit.Key)
+		}
+	}
+	return result
+}
+
+// GetExecutionOrder previews the order in which the units run when every event triggers them:
+// a topological order of the unit graph
+func (g *UnitGroup) GetExecutionOrder() ([]*Unit, error) {
+	if len(g.Units) == 0 {
+		return []*Unit{}, nil
+	}
+
+	nodes := graph.TopologicalSort(g)
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("unit group %s has a cycle, it has no execution order", g.Key)
+	}
+
+	result := make([]*Unit, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, node.(*Unit))
+	}
+	return result, nil
+}
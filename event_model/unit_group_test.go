@@ -0,0 +1,62 @@
+package event_model
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestInstanceWithoutNewInstanceKeepsGroupUnits(t *testing.T) {
+	a, b := newTestUnit("a", nil), newTestUnit("b", nil)
+	link(a, b)
+	group := &UnitGroup{Key: "g", Units: []*Unit{a, b}}
+
+	first := &UnitGroupInstance{Key: "first", UnitGroup: group}
+	if _, err := first.Run(context.Background(), nil, NewMemoryInstanceStore(), []string{testEventType}, nil); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	for _, unit := range first.GetUnits() {
+		if unit.State != UnitStateCompleted {
+			t.Errorf("unit %s of the instance is %s, want %s", unit.Key, unit.State, UnitStateCompleted)
+		}
+	}
+	for _, unit := range group.Units {
+		if !unit.isNotStarted() {
+			t.Errorf("unit %s of the group is %s, want it not started", unit.Key, unit.State)
+		}
+	}
+
+	second := &UnitGroupInstance{Key: "second", UnitGroup: group}
+	if state, _ := second.GetUnitState(context.Background(), "b"); state != UnitStateNotStarted {
+		t.Errorf("unit b of another instance is %s, want %s", state, UnitStateNotStarted)
+	}
+}
+
+func TestInstanceExportShowsUnitStates(t *testing.T) {
+	a, b := newTestUnit("a", nil), newTestUnit("b", nil)
+	link(a, b)
+	// an edge to a unit outside the group is not exported
+	link(a, newTestUnit("outside", nil))
+	group := &UnitGroup{Key: "g", Units: []*Unit{a, b}}
+
+	instance := group.NewInstance("i1")
+	if _, err := instance.Run(context.Background(), nil, NewMemoryInstanceStore(), []string{testEventType}, nil); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	dot := instance.ToDOT()
+	for _, want := range []string{`digraph "i1"`, `[completed]`, "n0 -> n1;", UnitStateColors[UnitStateCompleted]} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT of the instance has no %q:\n%s", want, dot)
+		}
+	}
+	if strings.Count(dot, "->") != 1 {
+		t.Errorf("DOT of the instance has %d edges, want 1:\n%s", strings.Count(dot, "->"), dot)
+	}
+
+	mermaid := group.ToMermaid()
+	if strings.Count(mermaid, "-->") != 1 || strings.Contains(mermaid, "[completed]") {
+		t.Errorf("Mermaid of the group has edges to outside units or instance states:\n%s", mermaid)
+	}
+}
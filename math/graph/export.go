@@ -0,0 +1,153 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExportOptions customizes how nodes are rendered by ToDOT and ToMermaid.
+// Every function is optional: nodes are named n0, n1... in the order of GetNodes and labelled by name.
+type ExportOptions struct {
+	Name      string            // graph name, G in DOT when empty, the Mermaid title when set
+	Direction string            // layout direction: TB (default) or LR
+	NodeID    func(Node) string // identifier, must be unique and made of letters, digits and _
+	NodeLabel func(Node) string // label, may contain line breaks
+	NodeColor func(Node) string // fill color, e.g. #a6e3a1, empty for none
+	EdgeLabel func(Edge) string // edge label, empty for none
+}
+
+// ToDOT renders the graph in the Graphviz DOT language, edges to nodes missing from GetNodes are left out
+func ToDOT(g Graph, opts *ExportOptions) string {
+	opts, ids := prepareExport(g, opts)
+
+	sb := &strings.Builder{}
+	name := opts.Name
+	if name == "" {
+		name = "G"
+	}
+	fmt.Fprintf(sb, "digraph %s {\n", quoteDOT(name))
+	fmt.Fprintf(sb, "  rankdir=%s;\n", opts.Direction)
+	sb.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=white];\n")
+
+	for _, node := range g.GetNodes() {
+		fmt.Fprintf(sb, "  %s [label=%s", ids[node], quoteDOT(opts.NodeLabel(node)))
+		if color := opts.NodeColor(node); color != "" {
+			fmt.Fprintf(sb, ", fillcolor=%s", quoteDOT(color))
+		}
+		sb.WriteString("];\n")
+	}
+
+	for _, edge := range g.GetEdges() {
+		from, to, ok := getEdgeIDs(edge, ids)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(sb, "  %s -> %s", from, to)
+		if label := opts.EdgeLabel(edge); label != "" {
+			fmt.Fprintf(sb, " [label=%s]", quoteDOT(label))
+		}
+		sb.WriteString(";\n")
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// ToMermaid renders the graph as a Mermaid flowchart, edges to nodes missing from GetNodes are left out
+func ToMermaid(g Graph, opts *ExportOptions) string {
+	opts, ids := prepareExport(g, opts)
+
+	sb := &strings.Builder{}
+	if opts.Name != "" {
+		fmt.Fprintf(sb, "---\ntitle: %s\n---\n", quoteYAML(opts.Name))
+	}
+	fmt.Fprintf(sb, "flowchart %s\n", opts.Direction)
+
+	for _, node := range g.GetNodes() {
+		fmt.Fprintf(sb, "  %s[%s]\n", ids[node], quoteMermaid(opts.NodeLabel(node)))
+	}
+
+	for _, edge := range g.GetEdges() {
+		from, to, ok := getEdgeIDs(edge, ids)
+		if !ok {
+			continue
+		}
+		if label := opts.EdgeLabel(edge); label != "" {
+			fmt.Fprintf(sb, "  %s -->|%s| %s\n", from, quoteMermaid(label), to)
+		} else {
+			fmt.Fprintf(sb, "  %s --> %s\n", from, to)
+		}
+	}
+
+	for _, node := range g.GetNodes() {
+		if color := opts.NodeColor(node); color != "" {
+			fmt.Fprintf(sb, "  style %s fill:%s\n", ids[node], color)
+		}
+	}
+
+	return sb.String()
+}
+
+// prepareExport fills the missing options and assigns the node identifiers
+func prepareExport(g Graph, opts *ExportOptions) (*ExportOptions, map[Node]string) {
+	result := ExportOptions{}
+	if opts != nil {
+		result = *opts
+	}
+
+	if result.Direction == "" {
+		result.Direction = "TB"
+	}
+
+	ids := make(map[Node]string)
+	for i, node := range g.GetNodes() {
+		if result.NodeID != nil {
+			ids[node] = result.NodeID(node)
+		} else {
+			ids[node] = fmt.Sprintf("n%d", i)
+		}
+	}
+
+	if result.NodeLabel == nil {
+		result.NodeLabel = func(node Node) string { return ids[node] }
+	}
+	if result.NodeColor == nil {
+		result.NodeColor = func(node Node) string { return "" }
+	}
+	if result.EdgeLabel == nil {
+		result.EdgeLabel = func(edge Edge) string { return "" }
+	}
+
+	return &result, ids
+}
+
+// getEdgeIDs returns the identifiers of the endpoints of the edge, ok is false when one of them is not
+// a node of the graph, such an edge is not exported
+func getEdgeIDs(edge Edge, ids map[Node]string) (from string, to string, ok bool) {
+	fromNode, toNode := edge.GetNodes()
+	if from, ok = ids[fromNode]; !ok {
+		return "", "", false
+	}
+	if to, ok = ids[toNode]; !ok {
+		return "", "", false
+	}
+	return from, to, true
+}
+
+// quoteDOT quotes a DOT string, line breaks become centered line breaks
+func quoteDOT(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+// quoteYAML quotes a YAML double-quoted string, for the title in the front matter of Mermaid diagrams
+func quoteYAML(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s)
+	return `"` + s + `"`
+}
+
+// quoteMermaid quotes a Mermaid label, quotes and angle brackets become entities and line breaks <br/>
+func quoteMermaid(s string) string {
+	s = strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "\n", "<br/>").Replace(s)
+	return `"` + s + `"`
+}
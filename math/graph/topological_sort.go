@@ -14,14 +14,14 @@ func TopologicalSort(graph Graph) []Node {
 
 	// 计算每个节点的实际入度
 	for _, edge := range edges {
-		from, _ := edge.GetNodes()
-		inDegree[from]++
+		_, to := edge.GetNodes()
+		inDegree[to]++
 	}
 
-	// 2. 将所有入度为0的节点加入队列
+	// 2. 将所有入度为0的节点按图中的顺序加入队列，保证结果稳定
 	queue := make([]Node, 0)
-	for node, degree := range inDegree {
-		if degree == 0 {
+	for _, node := range nodes {
+		if inDegree[node] == 0 {
 			queue = append(queue, node)
 		}
 	}
@@ -0,0 +1,75 @@
+package rule_engine
+
+import (
+	"sort"
+	"strings"
+)
+
+// Dependencies lists what a rule reads from its Environment
+type Dependencies struct {
+	Variables   []string `json:"variables"`    // root variable names, e.g. user
+	MemberPaths []string `json:"member_paths"` // full variable paths, e.g. user.address.city
+	Functions   []string `json:"functions"`    // called functions and methods, e.g. upper, user.FullName
+}
+
+// Analyze walks a compiled rule and collects the variables, member paths and functions it references.
+// Every list is sorted and free of duplicates, so the result can be used as part of a cache key.
+func Analyze(node NodeIf) *Dependencies {
+	variables := make(map[string]bool)
+	memberPaths := make(map[string]bool)
+	functions := make(map[string]bool)
+
+	var walk func(node NodeIf)
+	walk = func(node NodeIf) {
+		n, ok := node.(*NodeBase)
+		if !ok || n == nil {
+			return
+		}
+
+		switch n.GetType() {
+		case NodeTypeVariable:
+			if name, ok := GetVariableName(n); ok {
+				root, _, _ := strings.Cut(name, MemberSeparator)
+				variables[root] = true
+				memberPaths[name] = true
+			}
+		case NodeTypeCall:
+			name, _ := n.Params[NodeParamName].(string)
+			functions[name] = true
+
+			// A dotted call may be a method of a bound variable
+			if root, _, isMethod := strings.Cut(name, MemberSeparator); isMethod {
+				variables[root] = true
+			}
+		}
+
+		for _, child := range n.PreNodeList {
+			walk(child)
+		}
+		for _, child := range n.PostNodeList {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	return &Dependencies{
+		Variables:   sortedKeys(variables),
+		MemberPaths: sortedKeys(memberPaths),
+		Functions:   sortedKeys(functions),
+	}
+}
+
+// ReadsVariable reports whether the rule reads the variable, or any member of it
+func (d *Dependencies) ReadsVariable(name string) bool {
+	index := sort.SearchStrings(d.Variables, name)
+	return index < len(d.Variables) && d.Variables[index] == name
+}
+
+func sortedKeys(m map[string]bool) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -1 +1,16 @@
 package rule_engine
+
+// Compile parses a rule expression into a node tree.
+// The tree is read-only during evaluation and can be shared by concurrent evaluations.
+func Compile(expr string) (NodeIf, error) {
+	node, err := ParseExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// Evaluate evaluates a compiled rule against an environment
+func Evaluate(node NodeIf, env *Environment) (ValueIf, error) {
+	return node.EvaluateWithEnv(env)
+}
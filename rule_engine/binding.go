@@ -0,0 +1,334 @@
+package rule_engine
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BindingTag is the struct tag that names a field in rule expressions, the json tag is used when it is absent
+const BindingTag = "rule"
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// fieldIndexCache caches the field name to field index mapping of every bound struct type
+var fieldIndexCache sync.Map // map[reflect.Type]map[string][]int
+
+// BoundValue exposes an arbitrary Go value (struct, map, slice, pointer or time) as a ValueIf.
+// Fields, keys, elements and methods are only resolved when an expression accesses them.
+type BoundValue struct {
+	value reflect.Value
+}
+
+// Bind wraps a Go value into a ValueIf backed by reflection
+func Bind(value interface{}) ValueIf {
+	if v, ok := value.(ValueIf); ok {
+		return v
+	}
+	return &BoundValue{value: reflect.ValueOf(value)}
+}
+
+// indirect dereferences pointers and interfaces, the result is invalid for nil
+func (v *BoundValue) indirect() reflect.Value {
+	value := v.value
+	for value.IsValid() && (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+func (v *BoundValue) GetType() string {
+	value := v.indirect()
+	if !value.IsValid() {
+		return ValueTypeNull
+	}
+
+	if value.Type() == timeType {
+		return ValueTypeTime
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		return ValueTypeBool
+	case reflect.Int, reflect.Int64:
+		return ValueTypeInt64
+	case reflect.Int8:
+		return ValueTypeInt8
+	case reflect.Int16:
+		return ValueTypeInt16
+	case reflect.Int32:
+		return ValueTypeInt32
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return ValueTypeUint64
+	case reflect.Uint8:
+		return ValueTypeUint8
+	case reflect.Uint16:
+		return ValueTypeUint16
+	case reflect.Uint32:
+		return ValueTypeUint32
+	case reflect.Float32:
+		return ValueTypeFloat32
+	case reflect.Float64:
+		return ValueTypeFloat64
+	case reflect.Complex64:
+		return ValueTypeComplex64
+	case reflect.Complex128:
+		return ValueTypeComplex128
+	case reflect.String:
+		return ValueTypeString
+	case reflect.Slice, reflect.Array:
+		return ValueTypeArray
+	case reflect.Map:
+		return ValueTypeMap
+	case reflect.Struct:
+		return ValueTypeStruct
+	case reflect.Func:
+		return ValueTypeFunc
+	default:
+		return ValueTypeInterface
+	}
+}
+
+// GetValue returns the underlying Go value. Named primitive types are converted to their
+// underlying kind, slices to []interface{} and string keyed maps to map[string]interface{},
+// so the result works with the built-in operators.
+func (v *BoundValue) GetValue() interface{} {
+	value := v.indirect()
+	if !value.IsValid() {
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		return value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint()
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	case reflect.String:
+		return value.String()
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil
+		}
+		result := make([]interface{}, value.Len())
+		for i := range result {
+			result[i] = Bind(value.Index(i).Interface()).GetValue()
+		}
+		return result
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return value.Interface()
+		}
+		result := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			result[iter.Key().String()] = Bind(iter.Value().Interface()).GetValue()
+		}
+		return result
+	default:
+		if !value.CanInterface() {
+			return nil
+		}
+		return value.Interface()
+	}
+}
+
+// SetValue replaces the underlying value when it is addressable (bound through a pointer),
+// otherwise the bound value is replaced as a whole.
+func (v *BoundValue) SetValue(value interface{}) {
+	target := v.indirect()
+	if target.IsValid() && target.CanSet() {
+		newValue := reflect.ValueOf(value)
+		if newValue.IsValid() && newValue.Type().ConvertibleTo(target.Type()) {
+			target.Set(newValue.Convert(target.Type()))
+			return
+		}
+	}
+	v.value = reflect.ValueOf(value)
+}
+
+// GetMember resolves a struct field, a map key, a slice index or an exported method
+func (v *BoundValue) GetMember(name string) (ValueIf, bool) {
+	value := v.indirect()
+	if !value.IsValid() {
+		return nil, false
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if index, ok := getFieldIndex(value.Type())[name]; ok {
+			field, err := value.FieldByIndexErr(index)
+			if err != nil {
+				return &ValueBase{Type: ValueTypeNull}, true
+			}
+			return &BoundValue{value: field}, true
+		}
+	case reflect.Map:
+		if value.Type().Key().Kind() == reflect.String {
+			item := value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key()))
+			if item.IsValid() {
+				return &BoundValue{value: item}, true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < value.Len() {
+			return &BoundValue{value: value.Index(i)}, true
+		}
+	}
+
+	if method, ok := v.getMethod(name); ok {
+		return &ValueBase{Type: ValueTypeFunc, Value: methodToFunction(name, method)}, true
+	}
+
+	return nil, false
+}
+
+// getMethod looks the method up on the value and, for addressable values, on its pointer
+func (v *BoundValue) getMethod(name string) (reflect.Value, bool) {
+	candidates := []reflect.Value{v.value, v.indirect()}
+	if value := v.indirect(); value.IsValid() && value.CanAddr() {
+		candidates = append(candidates, value.Addr())
+	}
+
+	for _, candidate := range candidates {
+		if !candidate.IsValid() {
+			continue
+		}
+		if method := candidate.MethodByName(name); method.IsValid() {
+			return method, true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// getFieldIndex returns the expression names of the exported fields of a struct type,
+// including the fields promoted from embedded structs
+func getFieldIndex(t reflect.Type) map[string][]int {
+	if cached, ok := fieldIndexCache.Load(t); ok {
+		return cached.(map[string][]int)
+	}
+
+	fields := make([]reflect.StructField, 0, t.NumField())
+	for _, field := range reflect.VisibleFields(t) {
+		if field.IsExported() && !field.Anonymous {
+			fields = append(fields, field)
+		}
+	}
+
+	// Tagged names take precedence over Go field names, shallower fields over promoted ones
+	result := make(map[string][]int)
+	for _, field := range fields {
+		name := getFieldName(field)
+		if name == "-" {
+			continue
+		}
+		if index, exists := result[name]; !exists || len(field.Index) < len(index) {
+			result[name] = field.Index
+		}
+	}
+	for _, field := range fields {
+		if _, exists := result[field.Name]; !exists && getFieldName(field) != "-" {
+			result[field.Name] = field.Index
+		}
+	}
+
+	fieldIndexCache.Store(t, result)
+	return result
+}
+
+// getFieldName returns the name of a field from its rule tag, then its json tag, then its Go name
+func getFieldName(field reflect.StructField) string {
+	for _, tagKey := range []string{BindingTag, "json"} {
+		tag, ok := field.Tag.Lookup(tagKey)
+		if !ok {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if name != "" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+// methodToFunction adapts a reflected method to a Function. The method may return
+// nothing, a single value, or a value and an error.
+func methodToFunction(name string, method reflect.Value) Function {
+	return func(args ...ValueIf) (ValueIf, error) {
+		methodType := method.Type()
+		if !methodType.IsVariadic() && len(args) != methodType.NumIn() {
+			return nil, fmt.Errorf("method %s expects %d arguments, got %d", name, methodType.NumIn(), len(args))
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var paramType reflect.Type
+			if methodType.IsVariadic() && i >= methodType.NumIn()-1 {
+				paramType = methodType.In(methodType.NumIn() - 1).Elem()
+			} else {
+				paramType = methodType.In(i)
+			}
+
+			param, err := convertArgument(getValue(arg), paramType)
+			if err != nil {
+				return nil, fmt.Errorf("method %s argument %d: %w", name, i, err)
+			}
+			in[i] = param
+		}
+
+		out := method.Call(in)
+		switch len(out) {
+		case 0:
+			return &ValueBase{Type: ValueTypeNull}, nil
+		case 1:
+			if methodType.Out(0) == errorType {
+				err, _ := out[0].Interface().(error)
+				return &ValueBase{Type: ValueTypeNull}, err
+			}
+			return Bind(out[0].Interface()), nil
+		default:
+			if methodType.Out(len(out)-1) == errorType {
+				if err, _ := out[len(out)-1].Interface().(error); err != nil {
+					return nil, err
+				}
+			}
+			return Bind(out[0].Interface()), nil
+		}
+	}
+}
+
+// convertArgument converts an evaluated expression value to the type of a method parameter
+func convertArgument(arg interface{}, paramType reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		return reflect.Zero(paramType), nil
+	}
+
+	value := reflect.ValueOf(arg)
+	if value.Type().AssignableTo(paramType) {
+		return value, nil
+	}
+	// Go converts integers to strings as runes, which is never what an expression means
+	if paramType.Kind() == reflect.String && value.Kind() != reflect.String {
+		return reflect.Value{}, fmt.Errorf("cannot use %T as %s", arg, paramType)
+	}
+	if value.Type().ConvertibleTo(paramType) {
+		return value.Convert(paramType), nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot use %T as %s", arg, paramType)
+}
@@ -1,6 +1,199 @@
 package rule_engine
 
+import "sync"
+
+// Function is a function that can be called from a rule expression
+type Function func(args ...ValueIf) (ValueIf, error)
+
+// Environment scopes, from the outermost to the innermost layer
+const (
+	EnvScopeGlobal  = "global"
+	EnvScopeTenant  = "tenant"
+	EnvScopeRequest = "request"
+)
+
+// Environment holds the variables and functions visible to a rule evaluation.
+// Environments are layered: a lookup falls through to the parent layer when the name is not set
+// in the current one, and writes only ever touch the current layer. Clones share their maps until
+// the first write (copy-on-write), so one compiled rule can be evaluated in parallel, each goroutine
+// with its own request layer on top of a shared global or tenant layer.
 type Environment struct {
-	Variables map[string]ValueIf
-	Functions map[string]func(...ValueIf) (ValueIf, error)
+	mu        sync.RWMutex
+	scope     string
+	parent    *Environment
+	variables map[string]ValueIf
+	functions map[string]Function
+	shared    bool // maps are shared with a clone and must be copied before writing
+}
+
+// NewEnvironment creates an empty global environment
+func NewEnvironment() *Environment {
+	return &Environment{
+		scope:     EnvScopeGlobal,
+		variables: make(map[string]ValueIf),
+		functions: make(map[string]Function),
+	}
+}
+
+// NewChild creates an empty layer on top of the environment
+func (e *Environment) NewChild(scope string) *Environment {
+	return &Environment{
+		scope:     scope,
+		parent:    e,
+		variables: make(map[string]ValueIf),
+		functions: make(map[string]Function),
+	}
+}
+
+// Clone creates a copy of the current layer that shares the same parent.
+// The maps are only copied when either side is written to.
+func (e *Environment) Clone() *Environment {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.shared = true
+	return &Environment{
+		scope:     e.scope,
+		parent:    e.parent,
+		variables: e.variables,
+		functions: e.functions,
+		shared:    true,
+	}
+}
+
+func (e *Environment) GetScope() string {
+	return e.scope
+}
+
+func (e *Environment) GetParent() *Environment {
+	return e.parent
+}
+
+// Get looks up a variable, starting from the innermost layer
+func (e *Environment) Get(name string) (ValueIf, bool) {
+	for env := e; env != nil; env = env.parent {
+		env.mu.RLock()
+		value, ok := env.variables[name]
+		env.mu.RUnlock()
+
+		if ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// GetFunction looks up a function, starting from the innermost layer
+func (e *Environment) GetFunction(name string) (Function, bool) {
+	for env := e; env != nil; env = env.parent {
+		env.mu.RLock()
+		fn, ok := env.functions[name]
+		env.mu.RUnlock()
+
+		if ok {
+			return fn, true
+		}
+	}
+	return nil, false
+}
+
+// GetVariables returns a snapshot of all visible variables, inner layers shadowing outer ones
+func (e *Environment) GetVariables() map[string]ValueIf {
+	result := make(map[string]ValueIf)
+
+	for env := e; env != nil; env = env.parent {
+		env.mu.RLock()
+		for name, value := range env.variables {
+			if _, exists := result[name]; !exists {
+				result[name] = value
+			}
+		}
+		env.mu.RUnlock()
+	}
+
+	return result
+}
+
+// Set sets a variable in the current layer
+func (e *Environment) Set(name string, value ValueIf) *Environment {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.copyOnWrite()
+	e.variables[name] = value
+	return e
+}
+
+// SetFunction sets a function in the current layer
+func (e *Environment) SetFunction(name string, fn Function) *Environment {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.copyOnWrite()
+	e.functions[name] = fn
+	return e
+}
+
+// Delete removes a variable from the current layer, uncovering the parent's one if any
+func (e *Environment) Delete(name string) *Environment {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.variables[name]; !exists {
+		return e
+	}
+
+	e.copyOnWrite()
+	delete(e.variables, name)
+	return e
+}
+
+// SetValue binds a Go value through reflection and sets it in the current layer
+func (e *Environment) SetValue(name string, value interface{}) *Environment {
+	return e.Set(name, Bind(value))
+}
+
+func (e *Environment) SetBool(name string, value bool) *Environment {
+	return e.Set(name, &ValueBase{Type: ValueTypeBool, Value: value})
+}
+
+func (e *Environment) SetInt(name string, value int64) *Environment {
+	return e.Set(name, &ValueBase{Type: ValueTypeInt64, Value: value})
+}
+
+func (e *Environment) SetFloat(name string, value float64) *Environment {
+	return e.Set(name, &ValueBase{Type: ValueTypeFloat64, Value: value})
+}
+
+func (e *Environment) SetString(name string, value string) *Environment {
+	return e.Set(name, &ValueBase{Type: ValueTypeString, Value: value})
+}
+
+func (e *Environment) SetArray(name string, value []interface{}) *Environment {
+	return e.Set(name, &ValueBase{Type: ValueTypeArray, Value: value})
+}
+
+func (e *Environment) SetMap(name string, value map[string]interface{}) *Environment {
+	return e.Set(name, &ValueBase{Type: ValueTypeMap, Value: value})
+}
+
+// copyOnWrite detaches the maps of the current layer from its clones, the caller must hold the write lock
+func (e *Environment) copyOnWrite() {
+	if !e.shared {
+		return
+	}
+
+	variables := make(map[string]ValueIf, len(e.variables))
+	for name, value := range e.variables {
+		variables[name] = value
+	}
+
+	functions := make(map[string]Function, len(e.functions))
+	for name, fn := range e.functions {
+		functions[name] = fn
+	}
+
+	e.variables = variables
+	e.functions = functions
+	e.shared = false
 }
@@ -1,17 +1,30 @@
 package rule_engine
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type NodeIf interface {
 	GetType() string
 	Evaluate() (result ValueIf, err error)
+	EvaluateWithEnv(env *Environment) (result ValueIf, err error)
 }
 
 const (
-	NodeTypeExpr  = "expr"
-	NodeTypeValue = "value"
+	NodeTypeExpr     = "expr"
+	NodeTypeValue    = "value"
+	NodeTypeVariable = "variable"
+	NodeTypeCall     = "call"
+	NodeTypeArray    = "array"
 )
 
+// MemberSeparator separates the member path of a variable, e.g. user.address.city
+const MemberSeparator = "."
+
+// NodeParamName is the Params key holding the function name of a call node, or the path of a variable node
+const NodeParamName = "name"
+
 // ExprNode is the base structure for expression nodes in the rule engine.
 type NodeBase struct {
 	Type         string
@@ -22,31 +35,210 @@ type NodeBase struct {
 	PostNodeList []NodeIf               `json:"post_node_list"`
 }
 
+// ExprNode is the node type produced by the Parser.
+type ExprNode = NodeBase
+
+// NewValueNode creates a leaf node holding a literal
+func NewValueNode(value interface{}) *ExprNode {
+	return &ExprNode{
+		Type:  NodeTypeValue,
+		Value: NewValue(value),
+	}
+}
+
+// NewVariableNode creates a leaf node referencing a variable of the Environment by its member path
+func NewVariableNode(name string) *ExprNode {
+	return &ExprNode{
+		Type:   NodeTypeVariable,
+		Params: map[string]interface{}{NodeParamName: name},
+	}
+}
+
+// NewUnaryNode creates an operator node with a single operand
+func NewUnaryNode(operator string, operand NodeIf) *ExprNode {
+	return &ExprNode{
+		Type:         NodeTypeExpr,
+		Operator:     NewOperatorBase(operator),
+		PostNodeList: []NodeIf{operand},
+	}
+}
+
+// NewBinaryNode creates an operator node with a left and a right operand
+func NewBinaryNode(operator string, left, right NodeIf) *ExprNode {
+	return &ExprNode{
+		Type:         NodeTypeExpr,
+		Operator:     NewOperatorBase(operator),
+		PostNodeList: []NodeIf{left, right},
+	}
+}
+
+// NewCallNode creates a node calling a function from the Environment, or a method of a bound value
+func NewCallNode(name string, args ...NodeIf) *ExprNode {
+	return &ExprNode{
+		Type:         NodeTypeCall,
+		Params:       map[string]interface{}{NodeParamName: name},
+		PostNodeList: args,
+	}
+}
+
+// NewArrayNode creates a node building an array from its elements
+func NewArrayNode(elements ...NodeIf) *ExprNode {
+	return &ExprNode{
+		Type:         NodeTypeArray,
+		PostNodeList: elements,
+	}
+}
+
 func (n *NodeBase) GetType() string {
 	return n.Type
 }
 
-// Evaluate evaluates the expression node with given context (variables)
+// Evaluate evaluates the expression node without any variables
 func (n *NodeBase) Evaluate() (result ValueIf, err error) {
+	return n.EvaluateWithEnv(nil)
+}
+
+// EvaluateWithEnv evaluates the expression node with given context (variables).
+// The node tree is never modified, so one tree can be evaluated concurrently against different environments.
+func (n *NodeBase) EvaluateWithEnv(env *Environment) (result ValueIf, err error) {
+	return n.evaluate(env, nil)
+}
+
+// evaluate evaluates the node, recording every sub-expression but the literals to trace when it is set
+func (n *NodeBase) evaluate(env *Environment, trace *Trace) (result ValueIf, err error) {
 	switch n.GetType() {
-	case NodeTypeExpr:
+	case NodeTypeValue:
 		return n.Value, nil
 
-	case NodeTypeValue:
-		nodeListResult := make([]ValueIf, 0, len(n.PostNodeList))
+	case NodeTypeVariable:
+		name, _ := GetVariableName(n)
+		result, err = resolveVariable(env, name)
+
+	case NodeTypeExpr:
+		var args []ValueIf
+		if args, err = n.evaluatePostNodes(env, trace); err != nil {
+			return nil, err
+		}
+
+		result, err = n.Operator.Evaluate(args...)
+
+	case NodeTypeCall:
+		var fn Function
+		name, _ := n.Params[NodeParamName].(string)
+		if fn, err = resolveFunction(env, name); err != nil {
+			break
+		}
+
+		var args []ValueIf
+		if args, err = n.evaluatePostNodes(env, trace); err != nil {
+			return nil, err
+		}
+
+		result, err = fn(args...)
 
-		for _, node := range n.PostNodeList {
-			nodeResult, err := node.Evaluate()
-			if err != nil {
-				return nil, err
-			}
+	case NodeTypeArray:
+		var elements []ValueIf
+		if elements, err = n.evaluatePostNodes(env, trace); err != nil {
+			return nil, err
+		}
 
-			nodeListResult = append(nodeListResult, nodeResult)
+		array := make([]interface{}, len(elements))
+		for i, element := range elements {
+			array[i] = getValue(element)
 		}
 
-		return n.Operator.Evaluate(nodeListResult...)
+		result = &ValueBase{Type: ValueTypeArray, Value: array}
 
 	default:
-		return nil, fmt.Errorf("unknown node type: %s", n.GetType())
+		err = fmt.Errorf("unknown node type: %s", n.GetType())
+	}
+
+	trace.addStep(n, result, err)
+	return result, err
+}
+
+func (n *NodeBase) evaluatePostNodes(env *Environment, trace *Trace) ([]ValueIf, error) {
+	result := make([]ValueIf, 0, len(n.PostNodeList))
+
+	for _, node := range n.PostNodeList {
+		nodeResult, err := evaluateNode(node, env, trace)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, nodeResult)
+	}
+
+	return result, nil
+}
+
+// evaluateNode evaluates a node of the tree, nodes other than *NodeBase are recorded to trace as a whole
+func evaluateNode(node NodeIf, env *Environment, trace *Trace) (ValueIf, error) {
+	if n, ok := node.(*NodeBase); ok && n != nil {
+		return n.evaluate(env, trace)
+	}
+
+	result, err := node.EvaluateWithEnv(env)
+	trace.addStep(node, result, err)
+	return result, err
+}
+
+// resolveVariable looks up a variable and walks its member path
+func resolveVariable(env *Environment, name string) (ValueIf, error) {
+	path := strings.Split(name, MemberSeparator)
+
+	value, exists := env.Get(path[0])
+	if !exists {
+		return nil, fmt.Errorf("undefined variable: %s", path[0])
+	}
+
+	for i, member := range path[1:] {
+		memberValue, ok := value.(MemberValueIf)
+		if !ok {
+			memberValue, ok = Bind(value.GetValue()).(MemberValueIf)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s has no members", strings.Join(path[:i+1], MemberSeparator))
+		}
+
+		if value, ok = memberValue.GetMember(member); !ok {
+			return nil, fmt.Errorf("undefined member: %s", strings.Join(path[:i+2], MemberSeparator))
+		}
+	}
+
+	return value, nil
+}
+
+// resolveFunction looks up a function in the Environment, then as a method of a bound value
+func resolveFunction(env *Environment, name string) (Function, error) {
+	if fn, ok := env.GetFunction(name); ok {
+		return fn, nil
+	}
+
+	if !strings.Contains(name, MemberSeparator) {
+		return nil, fmt.Errorf("undefined function: %s", name)
 	}
+
+	value, err := resolveVariable(env, name)
+	if err != nil {
+		return nil, err
+	}
+
+	fn, ok := value.GetValue().(Function)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a function", name)
+	}
+
+	return fn, nil
+}
+
+// GetVariableName returns the variable name if the node is a variable reference
+func GetVariableName(node NodeIf) (string, bool) {
+	n, ok := node.(*NodeBase)
+	if !ok || n == nil || n.GetType() != NodeTypeVariable {
+		return "", false
+	}
+
+	name, ok := n.Params[NodeParamName].(string)
+	return name, ok
 }
@@ -2,10 +2,12 @@ package rule_engine
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type OperatorIf interface {
@@ -36,9 +38,24 @@ const (
 	OpTypeDivide       = "/"
 	OpTypeMod          = "%"
 	OpTypeExp          = "**"
-	OpTypeIn           = "in" // Membership/Set Operators
+	OpTypeIn           = "in"       // Membership/Set Operators
+	OpTypeContains     = "contains" // String Operators
+	OpTypeStartsWith   = "startsWith"
+	OpTypeEndsWith     = "endsWith"
+	OpTypeMatches      = "matches"
 )
 
+// IsComparisonOperator reports whether the operator compares two operands and yields a bool
+func IsComparisonOperator(operator string) bool {
+	switch operator {
+	case OpTypeEqual, OpTypeNotEqual, OpTypeLessThan, OpTypeLessEqual, OpTypeGreaterThan, OpTypeGreaterEqual, OpTypeIn,
+		OpTypeContains, OpTypeStartsWith, OpTypeEndsWith, OpTypeMatches:
+		return true
+	default:
+		return false
+	}
+}
+
 type OperatorBase struct {
 	Type string
 }
@@ -56,9 +73,9 @@ func (o *OperatorBase) GetType() string {
 func (o *OperatorBase) Evaluate(valueList ...ValueIf) (ValueIf, error) {
 	switch o.GetType() {
 	case OpTypeEqual:
-		return &ValueBase{Type: ValueTypeBool, Value: reflect.DeepEqual(getValue(valueList[0]), getValue(valueList[1]))}, nil
+		return &ValueBase{Type: ValueTypeBool, Value: equal(getValue(valueList[0]), getValue(valueList[1]))}, nil
 	case OpTypeNotEqual:
-		return &ValueBase{Type: ValueTypeBool, Value: !reflect.DeepEqual(getValue(valueList[0]), getValue(valueList[1]))}, nil
+		return &ValueBase{Type: ValueTypeBool, Value: !equal(getValue(valueList[0]), getValue(valueList[1]))}, nil
 	case OpTypeLessThan:
 		result, err := lessThan(getValue(valueList[0]), getValue(valueList[1]))
 		if err != nil {
@@ -89,6 +106,20 @@ func (o *OperatorBase) Evaluate(valueList ...ValueIf) (ValueIf, error) {
 			return nil, err
 		}
 		return &ValueBase{Type: ValueTypeBool, Value: result}, nil
+	case OpTypeContains, OpTypeStartsWith, OpTypeEndsWith, OpTypeMatches:
+		str, ok := getValue(valueList[0]).(string)
+		if !ok {
+			return nil, fmt.Errorf("left operand of %s must be string", o.GetType())
+		}
+		pattern, ok := getValue(valueList[1]).(string)
+		if !ok {
+			return nil, fmt.Errorf("right operand of %s must be string", o.GetType())
+		}
+		result, err := stringOperator(o.GetType(), str, pattern)
+		if err != nil {
+			return nil, err
+		}
+		return &ValueBase{Type: ValueTypeBool, Value: result}, nil
 	case OpTypeAnd:
 		leftBool, ok := getValue(valueList[0]).(bool)
 		if !ok {
@@ -201,30 +232,149 @@ func getValue(v ValueIf) interface{} {
 	if v == nil {
 		return nil
 	}
-	return v.GetValue()
+	return normalizeNumber(v.GetValue())
+}
+
+// normalizeNumber widens every integer kind to int and float32 to float64,
+// so literals, bound Go values and typed setters compare with each other.
+// Unsigned values above math.MaxInt stay uint64 rather than wrapping, operators then reject them.
+func normalizeNumber(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int8:
+		return int(n)
+	case int16:
+		return int(n)
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case uint:
+		if uint64(n) > math.MaxInt {
+			return uint64(n)
+		}
+		return int(n)
+	case uint8:
+		return int(n)
+	case uint16:
+		return int(n)
+	case uint32:
+		return int(n)
+	case uint64:
+		if n > math.MaxInt {
+			return n
+		}
+		return int(n)
+	case float32:
+		return float64(n)
+	}
+	return v
 }
 
 func getValueType(v interface{}) string {
 	switch v.(type) {
+	case nil:
+		return ValueTypeNull
 	case bool:
 		return ValueTypeBool
 	case string:
 		return ValueTypeString
+	case float32:
+		return ValueTypeFloat32
 	case float64:
 		return ValueTypeFloat64
-	case int64:
+	case int, int64:
 		return ValueTypeInt64
-	case uint64:
+	case int8:
+		return ValueTypeInt8
+	case int16:
+		return ValueTypeInt16
+	case int32:
+		return ValueTypeInt32
+	case uint, uint64:
 		return ValueTypeUint64
+	case uint8:
+		return ValueTypeUint8
+	case uint16:
+		return ValueTypeUint16
+	case uint32:
+		return ValueTypeUint32
+	case complex64:
+		return ValueTypeComplex64
+	case complex128:
+		return ValueTypeComplex128
 	case []interface{}:
 		return ValueTypeArray
 	case map[string]interface{}:
 		return ValueTypeMap
+	case time.Time:
+		return ValueTypeTime
+	}
+
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Slice, reflect.Array:
+		return ValueTypeArray
+	case reflect.Map:
+		return ValueTypeMap
+	case reflect.Struct:
+		return ValueTypeStruct
+	case reflect.Func:
+		return ValueTypeFunc
+	case reflect.Interface:
+		return ValueTypeInterface
 	default:
 		return ValueTypeNull
 	}
 }
 
+// equal compares numbers by value, so that 5 == 5.0, arrays element by element, times with time.Time.Equal
+// and anything else deeply.
+// The operands are expected to be normalized by normalizeNumber.
+func equal(a, b interface{}) bool {
+	switch av := a.(type) {
+	case int:
+		switch bv := b.(type) {
+		case int:
+			return av == bv
+		case float64:
+			return float64(av) == bv
+		}
+	case float64:
+		switch bv := b.(type) {
+		case float64:
+			return av == bv
+		case int:
+			return av == float64(bv)
+		case uint64:
+			return av == float64(bv)
+		}
+	case uint64:
+		switch bv := b.(type) {
+		case uint64:
+			return av == bv
+		case float64:
+			return float64(av) == bv
+		case int:
+			return false // normalized uint64 values are above math.MaxInt
+		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return av.Equal(bv)
+		}
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			break
+		}
+		for i := range av {
+			if !equal(normalizeNumber(av[i]), normalizeNumber(bv[i])) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
 // Comparison operators
 func lessThan(a, b interface{}) (bool, error) {
 	// Convert both values to comparable types
@@ -245,6 +395,10 @@ func lessThan(a, b interface{}) (bool, error) {
 		if bv, ok := b.(string); ok {
 			return av < bv, nil
 		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return av.Before(bv), nil
+		}
 	}
 	return false, fmt.Errorf("cannot compare %T and %T with <", a, b)
 }
@@ -268,6 +422,10 @@ func lessEqual(a, b interface{}) (bool, error) {
 		if bv, ok := b.(string); ok {
 			return av <= bv, nil
 		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return !av.After(bv), nil
+		}
 	}
 	return false, fmt.Errorf("cannot compare %T and %T with <=", a, b)
 }
@@ -291,6 +449,10 @@ func greaterThan(a, b interface{}) (bool, error) {
 		if bv, ok := b.(string); ok {
 			return av > bv, nil
 		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return av.After(bv), nil
+		}
 	}
 	return false, fmt.Errorf("cannot compare %T and %T with >", a, b)
 }
@@ -314,6 +476,10 @@ func greaterEqual(a, b interface{}) (bool, error) {
 		if bv, ok := b.(string); ok {
 			return av >= bv, nil
 		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return !av.Before(bv), nil
+		}
 	}
 	return false, fmt.Errorf("cannot compare %T and %T with >=", a, b)
 }
@@ -476,6 +642,21 @@ func matches(str, pattern string) (bool, error) {
 	return matched, nil
 }
 
+func stringOperator(operator, str, pattern string) (bool, error) {
+	switch operator {
+	case OpTypeContains:
+		return contains(str, pattern), nil
+	case OpTypeStartsWith:
+		return startsWith(str, pattern), nil
+	case OpTypeEndsWith:
+		return endsWith(str, pattern), nil
+	case OpTypeMatches:
+		return matches(str, pattern)
+	default:
+		return false, fmt.Errorf("unsupported string operator: %s", operator)
+	}
+}
+
 // Bitwise operations
 func bitwiseAnd(a, b interface{}) (interface{}, error) {
 	switch av := a.(type) {
@@ -616,7 +797,7 @@ func inOperator(needle, haystack interface{}) (bool, error) {
 	switch arr := haystack.(type) {
 	case []interface{}:
 		for _, item := range arr {
-			if reflect.DeepEqual(needle, item) {
+			if equal(needle, normalizeNumber(item)) {
 				return true, nil
 			}
 		}
@@ -0,0 +1,52 @@
+package rule_engine
+
+import (
+	"testing"
+)
+
+func TestEqualComparesNumbersByValue(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"5 == 5.0", true},
+		{"5.0 == 5", true},
+		{"5 != 5.0", false},
+		{"5 == 5.5", false},
+		{"i32 == 7", true},
+		{"f32 == 0.5", true},
+		{"u8 == 7.0", true},
+		{"big == 7", false},
+		{"name == \"a\"", true},
+		{"name == 1", false},
+		{"list == [1, 2]", true},
+		{"list == [1.0, 2]", true},
+		{"list == [1]", false},
+		{"7.0 in [1, 7]", true},
+		{"i32 in [7.0]", true},
+	}
+
+	env := NewEnvironment()
+	env.SetValue("i32", int32(7))
+	env.SetValue("f32", float32(0.5))
+	env.SetValue("u8", uint8(7))
+	env.SetValue("big", uint64(1)<<63)
+	env.SetValue("name", "a")
+	env.SetValue("list", []interface{}{1, 2})
+
+	for _, test := range tests {
+		node, err := Compile(test.expr)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", test.expr, err)
+		}
+
+		result, err := Evaluate(node, env)
+		if err != nil {
+			t.Errorf("Evaluate(%q): %v", test.expr, err)
+			continue
+		}
+		if got := result.GetValue(); got != test.want {
+			t.Errorf("%s = %v, want %v", test.expr, got, test.want)
+		}
+	}
+}
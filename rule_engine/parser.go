@@ -22,8 +22,14 @@ func NewParser(expr string) *Parser {
 }
 
 // Parse parses the expression and returns the root ExprNode
-func (p *Parser) Parse() (*ExprNode, error) {
-	node, err := p.parseExpression()
+func (p *Parser) Parse() (node *ExprNode, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			node, err = nil, fmt.Errorf("parse error: %v", r)
+		}
+	}()
+
+	node, err = p.parseExpression()
 	if err != nil {
 		return nil, err
 	}
@@ -43,14 +49,14 @@ func (p *Parser) parseExpression() (*ExprNode, error) {
 		return nil, err
 	}
 
-	for p.peek() == '|' {
+	for p.peekNext() == '|' {
 		p.consume('|')
 		p.consume('|')
 		right, err := p.parseAndExpression()
 		if err != nil {
 			return nil, err
 		}
-		left = NewBinaryNode(OpOr, left, right)
+		left = NewBinaryNode(OpTypeOr, left, right)
 	}
 
 	return left, nil
@@ -63,14 +69,14 @@ func (p *Parser) parseAndExpression() (*ExprNode, error) {
 		return nil, err
 	}
 
-	for p.peek() == '&' {
+	for p.peekNext() == '&' {
 		p.consume('&')
 		p.consume('&')
 		right, err := p.parseComparison()
 		if err != nil {
 			return nil, err
 		}
-		left = NewBinaryNode(OpAnd, left, right)
+		left = NewBinaryNode(OpTypeAnd, left, right)
 	}
 
 	return left, nil
@@ -110,21 +116,21 @@ func (p *Parser) parseAdditive() (*ExprNode, error) {
 	}
 
 	for {
-		op := p.peek()
+		op := p.peekNext()
 		if op == '+' {
 			p.consume('+')
 			right, err := p.parseMultiplicative()
 			if err != nil {
 				return nil, err
 			}
-			left = NewBinaryNode(OpAdd, left, right)
+			left = NewBinaryNode(OpTypeAdd, left, right)
 		} else if op == '-' {
 			p.consume('-')
 			right, err := p.parseMultiplicative()
 			if err != nil {
 				return nil, err
 			}
-			left = NewBinaryNode(OpSubtract, left, right)
+			left = NewBinaryNode(OpTypeSubtract, left, right)
 		} else {
 			break
 		}
@@ -141,28 +147,28 @@ func (p *Parser) parseMultiplicative() (*ExprNode, error) {
 	}
 
 	for {
-		op := p.peek()
+		op := p.peekNext()
 		if op == '*' {
 			p.consume('*')
 			right, err := p.parseUnary()
 			if err != nil {
 				return nil, err
 			}
-			left = NewBinaryNode(OpMultiply, left, right)
+			left = NewBinaryNode(OpTypeMultiply, left, right)
 		} else if op == '/' {
 			p.consume('/')
 			right, err := p.parseUnary()
 			if err != nil {
 				return nil, err
 			}
-			left = NewBinaryNode(OpDivide, left, right)
+			left = NewBinaryNode(OpTypeDivide, left, right)
 		} else if op == '%' {
 			p.consume('%')
 			right, err := p.parseUnary()
 			if err != nil {
 				return nil, err
 			}
-			left = NewBinaryNode(OpMod, left, right)
+			left = NewBinaryNode(OpTypeMod, left, right)
 		} else {
 			break
 		}
@@ -173,13 +179,13 @@ func (p *Parser) parseMultiplicative() (*ExprNode, error) {
 
 // parseUnary parses unary expressions (!)
 func (p *Parser) parseUnary() (*ExprNode, error) {
-	if p.peek() == '!' {
+	if p.peekNext() == '!' {
 		p.consume('!')
 		right, err := p.parseUnary()
 		if err != nil {
 			return nil, err
 		}
-		return NewUnaryNode(OpNot, right), nil
+		return NewUnaryNode(OpTypeNot, right), nil
 	}
 
 	return p.parsePrimary()
@@ -204,6 +210,11 @@ func (p *Parser) parsePrimary() (*ExprNode, error) {
 		return node, nil
 	}
 
+	// Handle array literals
+	if p.peek() == '[' {
+		return p.parseArray()
+	}
+
 	// Handle string literals (enclosed in quotes)
 	if p.peek() == '"' || p.peek() == '\'' {
 		return p.parseStringLiteral()
@@ -218,6 +229,27 @@ func (p *Parser) parsePrimary() (*ExprNode, error) {
 	return p.parseIdentifier()
 }
 
+// parseArray parses an array literal, e.g. [1, 2, x]
+func (p *Parser) parseArray() (*ExprNode, error) {
+	p.consume('[')
+
+	elements := make([]NodeIf, 0)
+	for p.peekNext() != ']' {
+		if len(elements) > 0 {
+			p.consume(',')
+		}
+
+		element, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+
+	p.consume(']')
+	return NewArrayNode(elements...), nil
+}
+
 // parseStringLiteral parses a string literal
 func (p *Parser) parseStringLiteral() (*ExprNode, error) {
 	quote := p.peek()
@@ -274,19 +306,42 @@ func (p *Parser) parseNumber() (*ExprNode, error) {
 	return nil, fmt.Errorf("invalid number: %s", numStr)
 }
 
-// parseIdentifier parses identifiers (variables, booleans)
+// parseIdentifier parses identifiers (variables, member paths, function calls, booleans)
 func (p *Parser) parseIdentifier() (*ExprNode, error) {
 	p.skipWhitespace()
 	start := p.pos
 
-	// Parse identifier
-	for p.pos < len(p.expr) && (isLetter(p.peek()) || isDigit(p.peek()) || p.peek() == '_' || p.peek() == '$') {
-		p.consume(p.peek())
+	// Parse identifier and its member path, e.g. user.address.city
+	for {
+		segmentStart := p.pos
+		for p.pos < len(p.expr) && isIdentifierChar(p.peek()) {
+			p.consume(p.peek())
+		}
+
+		if p.pos == segmentStart {
+			if p.pos == start {
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", p.peek(), p.pos)
+			}
+			return nil, fmt.Errorf("expected member name at position %d", p.pos)
+		}
+
+		if p.peek() != '.' {
+			break
+		}
+		p.consume('.')
 	}
 
 	id := p.expr[start:p.pos]
 
-	// Check for boolean literals
+	// Check for function calls
+	if p.peekNext() == '(' {
+		return p.parseCall(id)
+	}
+
+	// Check for null and boolean literals
+	if id == "null" {
+		return NewValueNode(nil), nil
+	}
 	if id == "true" {
 		return NewValueNode(true), nil
 	}
@@ -295,7 +350,28 @@ func (p *Parser) parseIdentifier() (*ExprNode, error) {
 	}
 
 	// Treat as variable
-	return NewValueNode("$" + id), nil
+	return NewVariableNode(id), nil
+}
+
+// parseCall parses the argument list of a function call
+func (p *Parser) parseCall(name string) (*ExprNode, error) {
+	p.consume('(')
+
+	args := make([]NodeIf, 0)
+	for p.peekNext() != ')' {
+		if len(args) > 0 {
+			p.consume(',')
+		}
+
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+
+	p.consume(')')
+	return NewCallNode(name, args...), nil
 }
 
 // parseOperator parses an operator
@@ -313,19 +389,33 @@ func (p *Parser) parseOperator() string {
 		case "==":
 			p.consume('=')
 			p.consume('=')
-			return OpEqual
+			return OpTypeEqual
 		case "!=":
 			p.consume('!')
 			p.consume('=')
-			return OpNotEqual
+			return OpTypeNotEqual
 		case "<=":
 			p.consume('<')
 			p.consume('=')
-			return OpLessEqual
+			return OpTypeLessEqual
 		case ">=":
 			p.consume('>')
 			p.consume('=')
-			return OpGreaterEqual
+			return OpTypeGreaterEqual
+		}
+	}
+
+	// Keyword operators, e.g. x in [1, 2], name startsWith "a"
+	if isLetter(p.peek()) {
+		end := p.pos
+		for end < len(p.expr) && isIdentifierChar(rune(p.expr[end])) {
+			end++
+		}
+
+		switch word := p.expr[p.pos:end]; word {
+		case OpTypeIn, OpTypeContains, OpTypeStartsWith, OpTypeEndsWith, OpTypeMatches:
+			p.pos = end
+			return word
 		}
 	}
 
@@ -333,13 +423,13 @@ func (p *Parser) parseOperator() string {
 	switch p.peek() {
 	case '<':
 		p.consume('<')
-		return OpLessThan
+		return OpTypeLessThan
 	case '>':
 		p.consume('>')
-		return OpGreaterThan
+		return OpTypeGreaterThan
 	case '=':
 		p.consume('=')
-		return OpEqual
+		return OpTypeEqual
 	}
 
 	return ""
@@ -354,6 +444,12 @@ func (p *Parser) peek() rune {
 	return rune(p.expr[p.pos])
 }
 
+// peekNext skips whitespace and returns the next significant character
+func (p *Parser) peekNext() rune {
+	p.skipWhitespace()
+	return p.peek()
+}
+
 func (p *Parser) consume(expected rune) {
 	if p.pos >= len(p.expr) || rune(p.expr[p.pos]) != expected {
 		panic(fmt.Sprintf("expected '%c' but found '%c' at position %d", expected, p.peek(), p.pos))
@@ -375,6 +471,10 @@ func isLetter(r rune) bool {
 	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
 }
 
+func isIdentifierChar(r rune) bool {
+	return isLetter(r) || isDigit(r) || r == '_' || r == '$'
+}
+
 // ParseExpression is a convenience function to parse an expression string
 func ParseExpression(expr string) (*ExprNode, error) {
 	parser := NewParser(expr)
@@ -0,0 +1,63 @@
+package rule_engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// celStringMethods maps CEL string member functions onto the string operators
+var celStringMethods = map[string]string{
+	"contains":   OpTypeContains,
+	"startsWith": OpTypeStartsWith,
+	"endsWith":   OpTypeEndsWith,
+	"matches":    OpTypeMatches,
+}
+
+// ParseCEL parses a simple CEL expression into a node tree. The shared subset of the grammar
+// (literals, null, member access, comparisons, in, arithmetic, !, && and ||) is handled by the
+// expression parser, then s.startsWith("a") style string functions are rewritten into string
+// operators. Macros, ternaries and receivers other than variables are not supported.
+func ParseCEL(expr string) (*ExprNode, error) {
+	node, err := ParseExpression(expr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cel expression: %w", err)
+	}
+
+	return rewriteCEL(node)
+}
+
+func rewriteCEL(node *ExprNode) (*ExprNode, error) {
+	for i, child := range node.PostNodeList {
+		childNode, ok := child.(*ExprNode)
+		if !ok {
+			continue
+		}
+
+		rewritten, err := rewriteCEL(childNode)
+		if err != nil {
+			return nil, err
+		}
+		node.PostNodeList[i] = rewritten
+	}
+
+	if node.GetType() == NodeTypeCall {
+		name, _ := node.Params[NodeParamName].(string)
+		index := strings.LastIndex(name, MemberSeparator)
+		if index < 0 {
+			return node, nil
+		}
+
+		operator, ok := celStringMethods[name[index+1:]]
+		if !ok {
+			return node, nil
+		}
+
+		if len(node.PostNodeList) != 1 {
+			return nil, fmt.Errorf("cel function %s expects 1 argument, got %d", name, len(node.PostNodeList))
+		}
+
+		return NewBinaryNode(operator, NewVariableNode(name[:index]), node.PostNodeList[0]), nil
+	}
+
+	return node, nil
+}
@@ -0,0 +1,172 @@
+package rule_engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// jsonLogicOperators maps JSON-logic operators onto the operators of the node tree
+var jsonLogicOperators = map[string]string{
+	"==":  OpTypeEqual,
+	"===": OpTypeEqual,
+	"!=":  OpTypeNotEqual,
+	"!==": OpTypeNotEqual,
+	"<":   OpTypeLessThan,
+	"<=":  OpTypeLessEqual,
+	">":   OpTypeGreaterThan,
+	">=":  OpTypeGreaterEqual,
+	"and": OpTypeAnd,
+	"or":  OpTypeOr,
+	"!":   OpTypeNot,
+	"in":  OpTypeIn,
+	"+":   OpTypeAdd,
+	"-":   OpTypeSubtract,
+	"*":   OpTypeMultiply,
+	"/":   OpTypeDivide,
+	"%":   OpTypeMod,
+}
+
+// ParseJSONLogic parses a JSON-logic rule, e.g. {"and":[{">":[{"var":"age"},18]}]}, into a node tree
+func ParseJSONLogic(data []byte) (*ExprNode, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var rule interface{}
+	if err := decoder.Decode(&rule); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal json logic: %w", err)
+	}
+
+	return FromJSONLogic(rule)
+}
+
+// FromJSONLogic converts an already decoded JSON-logic rule into a node tree
+func FromJSONLogic(rule interface{}) (*ExprNode, error) {
+	switch value := rule.(type) {
+	case map[string]interface{}:
+		if len(value) != 1 {
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			return nil, fmt.Errorf("json logic operation must have exactly one operator, got %v", keys)
+		}
+
+		for operator, args := range value {
+			return fromJSONLogicOperation(operator, args)
+		}
+
+	case []interface{}:
+		elements := make([]NodeIf, len(value))
+		for i, item := range value {
+			element, err := FromJSONLogic(item)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return NewArrayNode(elements...), nil
+
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return NewValueNode(i), nil
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid number: %s", value)
+		}
+		return NewValueNode(f), nil
+
+	case float64:
+		// Integral numbers decoded without UseNumber are kept as integers, like the expression parser does
+		if value == math.Trunc(value) && math.Abs(value) < math.MaxInt64 {
+			return NewValueNode(int64(value)), nil
+		}
+		return NewValueNode(value), nil
+
+	case string, bool, nil:
+		return NewValueNode(value), nil
+	}
+
+	return nil, fmt.Errorf("unsupported json logic value %T", rule)
+}
+
+func fromJSONLogicOperation(operator string, args interface{}) (*ExprNode, error) {
+	// Unary operations may omit the argument array, e.g. {"var":"age"} or {"!":true}
+	argList, ok := args.([]interface{})
+	if !ok {
+		argList = []interface{}{args}
+	}
+
+	if operator == "var" {
+		return fromJSONLogicVar(argList)
+	}
+
+	op, ok := jsonLogicOperators[operator]
+	if !ok {
+		return nil, fmt.Errorf("unsupported json logic operator: %s", operator)
+	}
+
+	operands := make([]*ExprNode, len(argList))
+	for i, arg := range argList {
+		operand, err := FromJSONLogic(arg)
+		if err != nil {
+			return nil, err
+		}
+		operands[i] = operand
+	}
+
+	switch {
+	case op == OpTypeNot:
+		if len(operands) != 1 {
+			return nil, fmt.Errorf("json logic operator %s expects 1 argument, got %d", operator, len(operands))
+		}
+		return NewUnaryNode(OpTypeNot, operands[0]), nil
+
+	case op == OpTypeSubtract && len(operands) == 1:
+		return NewBinaryNode(OpTypeSubtract, NewValueNode(int64(0)), operands[0]), nil
+
+	case (op == OpTypeLessThan || op == OpTypeLessEqual) && len(operands) == 3:
+		// Between: {"<":[1, x, 3]} is 1 < x && x < 3
+		return NewBinaryNode(OpTypeAnd,
+			NewBinaryNode(op, operands[0], operands[1]),
+			NewBinaryNode(op, operands[1], operands[2]),
+		), nil
+
+	case op == OpTypeAnd || op == OpTypeOr || op == OpTypeAdd || op == OpTypeMultiply:
+		// Variadic operations are folded from the left
+		if len(operands) == 0 {
+			return nil, fmt.Errorf("json logic operator %s expects at least 1 argument", operator)
+		}
+		node := operands[0]
+		for _, operand := range operands[1:] {
+			node = NewBinaryNode(op, node, operand)
+		}
+		return node, nil
+
+	default:
+		if len(operands) != 2 {
+			return nil, fmt.Errorf("json logic operator %s expects 2 arguments, got %d", operator, len(operands))
+		}
+		return NewBinaryNode(op, operands[0], operands[1]), nil
+	}
+}
+
+func fromJSONLogicVar(args []interface{}) (*ExprNode, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("json logic var expects a path")
+	}
+	if len(args) > 1 {
+		return nil, fmt.Errorf("json logic var default values are not supported")
+	}
+
+	path, ok := args[0].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("json logic var path must be a non-empty string, got %v", args[0])
+	}
+
+	return NewVariableNode(path), nil
+}
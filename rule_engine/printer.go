@@ -0,0 +1,141 @@
+package rule_engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// nativeOperators maps the operators to their expression syntax, operators missing here
+// cannot be parsed back by the expression parser
+var nativeOperators = map[string]string{
+	OpTypeEqual:        "==",
+	OpTypeNotEqual:     "!=",
+	OpTypeLessThan:     "<",
+	OpTypeLessEqual:    "<=",
+	OpTypeGreaterThan:  ">",
+	OpTypeGreaterEqual: ">=",
+	OpTypeAnd:          "&&",
+	OpTypeOr:           "||",
+	OpTypeNot:          "!",
+	OpTypeAdd:          "+",
+	OpTypeSubtract:     "-",
+	OpTypeMultiply:     "*",
+	OpTypeDivide:       "/",
+	OpTypeMod:          "%",
+	OpTypeIn:           OpTypeIn,
+	OpTypeContains:     OpTypeContains,
+	OpTypeStartsWith:   OpTypeStartsWith,
+	OpTypeEndsWith:     OpTypeEndsWith,
+	OpTypeMatches:      OpTypeMatches,
+}
+
+// ToExpression renders a node tree as native expression text, so rules parsed from other
+// syntaxes can be stored and edited in the native one. Every operation is parenthesized.
+func ToExpression(node NodeIf) (string, error) {
+	var sb strings.Builder
+	if err := writeExpression(&sb, node); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func writeExpression(sb *strings.Builder, node NodeIf) error {
+	n, ok := node.(*NodeBase)
+	if !ok || n == nil {
+		return fmt.Errorf("cannot print node %T", node)
+	}
+
+	switch n.GetType() {
+	case NodeTypeValue:
+		return writeLiteral(sb, getValue(n.Value))
+
+	case NodeTypeVariable:
+		name, _ := GetVariableName(n)
+		sb.WriteString(name)
+		return nil
+
+	case NodeTypeExpr:
+		operator, ok := nativeOperators[n.Operator.GetType()]
+		if !ok {
+			return fmt.Errorf("operator %s has no expression syntax", n.Operator.GetType())
+		}
+
+		if len(n.PostNodeList) == 1 {
+			sb.WriteString(operator)
+			return writeExpression(sb, n.PostNodeList[0])
+		}
+		if len(n.PostNodeList) != 2 {
+			return fmt.Errorf("operator %s expects 2 operands, got %d", operator, len(n.PostNodeList))
+		}
+
+		sb.WriteString("(")
+		if err := writeExpression(sb, n.PostNodeList[0]); err != nil {
+			return err
+		}
+		sb.WriteString(" " + operator + " ")
+		if err := writeExpression(sb, n.PostNodeList[1]); err != nil {
+			return err
+		}
+		sb.WriteString(")")
+		return nil
+
+	case NodeTypeCall:
+		name, _ := n.Params[NodeParamName].(string)
+		sb.WriteString(name)
+		return writeList(sb, "(", n.PostNodeList, ")")
+
+	case NodeTypeArray:
+		return writeList(sb, "[", n.PostNodeList, "]")
+
+	default:
+		return fmt.Errorf("unknown node type: %s", n.GetType())
+	}
+}
+
+func writeList(sb *strings.Builder, open string, nodes []NodeIf, close string) error {
+	sb.WriteString(open)
+	for i, node := range nodes {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if err := writeExpression(sb, node); err != nil {
+			return err
+		}
+	}
+	sb.WriteString(close)
+	return nil
+}
+
+func writeLiteral(sb *strings.Builder, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		sb.WriteString("null")
+	case bool:
+		sb.WriteString(strconv.FormatBool(v))
+	case int:
+		sb.WriteString(strconv.Itoa(v))
+	case float64:
+		str := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.Contains(str, ".") {
+			str += ".0" // keep the literal a float when parsed back
+		}
+		sb.WriteString(str)
+	case string:
+		sb.WriteString(`"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`)
+	case []interface{}:
+		sb.WriteString("[")
+		for i, item := range v {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if err := writeLiteral(sb, item); err != nil {
+				return err
+			}
+		}
+		sb.WriteString("]")
+	default:
+		return fmt.Errorf("literal %v (%T) has no expression syntax", value, value)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package rule_engine
+
+import (
+	"context"
+	"fmt"
+	logger "log/slog"
+	"reflect"
+	"sync/atomic"
+)
+
+// ShadowDisagreement records a rule for which the shadow rule set disagreed with the live one
+type ShadowDisagreement struct {
+	Key           string
+	LiveVersion   string
+	ShadowVersion string
+	LiveResult    ValueIf
+	ShadowResult  ValueIf
+	LiveErr       error
+	ShadowErr     error
+}
+
+// RuleManager holds the live RuleSet loaded from a RuleRepository and swaps it atomically on reload.
+// An optional shadow RuleSet is evaluated alongside the live one, its results are only compared and logged.
+type RuleManager struct {
+	repository     RuleRepository
+	live           atomic.Pointer[RuleSet]
+	shadow         atomic.Pointer[RuleSet]
+	OnDisagreement func(ctx context.Context, disagreement *ShadowDisagreement)
+}
+
+func NewRuleManager(repository RuleRepository) *RuleManager {
+	return &RuleManager{
+		repository: repository,
+	}
+}
+
+// GetRuleSet returns the live rule set, nil before the first load
+func (m *RuleManager) GetRuleSet() *RuleSet {
+	return m.live.Load()
+}
+
+// GetShadowRuleSet returns the shadow rule set, nil when shadow mode is off
+func (m *RuleManager) GetShadowRuleSet() *RuleSet {
+	return m.shadow.Load()
+}
+
+// SetRuleSet replaces the live rule set
+func (m *RuleManager) SetRuleSet(ruleSet *RuleSet) {
+	m.live.Store(ruleSet)
+}
+
+// SetShadowRuleSet turns shadow mode on with the candidate rule set, or off with nil
+func (m *RuleManager) SetShadowRuleSet(ruleSet *RuleSet) {
+	m.shadow.Store(ruleSet)
+}
+
+// Reload compiles the latest version from the repository and makes it live.
+// The live rule set is left untouched if loading or compiling fails.
+func (m *RuleManager) Reload(ctx context.Context) error {
+	definition, err := m.repository.LoadLatest(ctx)
+	if err != nil {
+		return err
+	}
+
+	ruleSet, err := CompileRuleSet(definition)
+	if err != nil {
+		return err
+	}
+
+	m.live.Store(ruleSet)
+	logger.InfoContext(ctx, fmt.Sprintf("rule set reloaded, version: %s", ruleSet.Version))
+	return nil
+}
+
+// LoadShadow compiles the given version from the repository and evaluates it in shadow mode
+func (m *RuleManager) LoadShadow(ctx context.Context, version string) error {
+	definition, err := m.repository.Load(ctx, version)
+	if err != nil {
+		return err
+	}
+
+	ruleSet, err := CompileRuleSet(definition)
+	if err != nil {
+		return err
+	}
+
+	m.shadow.Store(ruleSet)
+	return nil
+}
+
+// Promote makes the shadow rule set live and turns shadow mode off
+func (m *RuleManager) Promote(ctx context.Context) error {
+	shadow := m.shadow.Load()
+	if shadow == nil {
+		return fmt.Errorf("no shadow rule set to promote")
+	}
+
+	m.live.Store(shadow)
+	m.shadow.CompareAndSwap(shadow, nil)
+	logger.InfoContext(ctx, fmt.Sprintf("shadow rule set promoted, version: %s", shadow.Version))
+	return nil
+}
+
+// Watch reloads the rule set whenever the repository reports a change. It blocks until the
+// context is done and requires the repository to implement RuleWatcher.
+func (m *RuleManager) Watch(ctx context.Context) error {
+	watcher, ok := m.repository.(RuleWatcher)
+	if !ok {
+		return fmt.Errorf("rule repository %T does not support watching", m.repository)
+	}
+
+	return watcher.Watch(ctx, func() {
+		if err := m.Reload(ctx); err != nil {
+			logger.ErrorContext(ctx, fmt.Sprintf("rule set reload failed: %v", err))
+		}
+	})
+}
+
+// Evaluate evaluates the rule by key with the live rule set. In shadow mode the shadow rule set
+// is evaluated too and every disagreement is logged, the shadow result never affects the return value.
+func (m *RuleManager) Evaluate(ctx context.Context, key string, env *Environment) (ValueIf, error) {
+	live := m.live.Load()
+	if live == nil {
+		return nil, fmt.Errorf("no rule set loaded")
+	}
+
+	result, err := live.Evaluate(key, env)
+
+	if shadow := m.shadow.Load(); shadow != nil {
+		shadowResult, shadowErr := shadow.Evaluate(key, env)
+		if !sameResult(result, err, shadowResult, shadowErr) {
+			m.reportDisagreement(ctx, &ShadowDisagreement{
+				Key:           key,
+				LiveVersion:   live.Version,
+				ShadowVersion: shadow.Version,
+				LiveResult:    result,
+				ShadowResult:  shadowResult,
+				LiveErr:       err,
+				ShadowErr:     shadowErr,
+			})
+		}
+	}
+
+	return result, err
+}
+
+func (m *RuleManager) reportDisagreement(ctx context.Context, d *ShadowDisagreement) {
+	logger.WarnContext(ctx, fmt.Sprintf("shadow rule disagreement, key: %s, live: %s (%v, %v), shadow: %s (%v, %v)",
+		d.Key,
+		d.LiveVersion, getValue(d.LiveResult), d.LiveErr,
+		d.ShadowVersion, getValue(d.ShadowResult), d.ShadowErr,
+	))
+
+	if m.OnDisagreement != nil {
+		m.OnDisagreement(ctx, d)
+	}
+}
+
+// sameResult reports whether two evaluations agree: both failed, or both returned equal values
+func sameResult(a ValueIf, aErr error, b ValueIf, bErr error) bool {
+	if aErr != nil || bErr != nil {
+		return aErr != nil && bErr != nil
+	}
+	return reflect.DeepEqual(getValue(a), getValue(b))
+}
@@ -0,0 +1,150 @@
+package rule_engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RuleRepository stores versioned rule set definitions
+type RuleRepository interface {
+	GetVersions(ctx context.Context) ([]string, error)
+	Load(ctx context.Context, version string) (*RuleSetDefinition, error)
+	LoadLatest(ctx context.Context) (*RuleSetDefinition, error)
+}
+
+// RuleWatcher is implemented by repositories that can notify about changes
+type RuleWatcher interface {
+	Watch(ctx context.Context, onChange func()) error
+}
+
+const (
+	ruleFileExt              = ".json"
+	defaultRuleWatchInterval = 5 * time.Second
+)
+
+// FileRuleRepository stores one JSON encoded RuleSetDefinition per version in a directory,
+// named <version>.json. Versions are ordered lexically, so they should be zero padded
+// numbers or timestamps.
+type FileRuleRepository struct {
+	Dir           string
+	WatchInterval time.Duration
+}
+
+func NewFileRuleRepository(dir string) *FileRuleRepository {
+	return &FileRuleRepository{
+		Dir:           dir,
+		WatchInterval: defaultRuleWatchInterval,
+	}
+}
+
+func (r *FileRuleRepository) GetVersions(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rule directory: %w", err)
+	}
+
+	versions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ruleFileExt {
+			continue
+		}
+		versions = append(versions, strings.TrimSuffix(entry.Name(), ruleFileExt))
+	}
+
+	sort.Strings(versions)
+	return versions, nil
+}
+
+func (r *FileRuleRepository) Load(ctx context.Context, version string) (*RuleSetDefinition, error) {
+	data, err := os.ReadFile(filepath.Join(r.Dir, version+ruleFileExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rule set %s: %w", version, err)
+	}
+
+	definition := &RuleSetDefinition{}
+	if err := json.Unmarshal(data, definition); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rule set %s: %w", version, err)
+	}
+
+	if definition.Version == "" {
+		definition.Version = version
+	}
+
+	return definition, nil
+}
+
+func (r *FileRuleRepository) LoadLatest(ctx context.Context) (*RuleSetDefinition, error) {
+	versions, err := r.GetVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no rule set found in %s", r.Dir)
+	}
+
+	return r.Load(ctx, versions[len(versions)-1])
+}
+
+// Watch polls the directory and calls onChange whenever a rule file is added, removed or modified.
+// It blocks until the context is done.
+func (r *FileRuleRepository) Watch(ctx context.Context, onChange func()) error {
+	interval := r.WatchInterval
+	if interval <= 0 {
+		interval = defaultRuleWatchInterval
+	}
+
+	last, err := r.snapshot()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			current, err := r.snapshot()
+			if err != nil {
+				continue
+			}
+
+			if current != last {
+				last = current
+				onChange()
+			}
+		}
+	}
+}
+
+// snapshot summarizes the names, sizes and modification times of the rule files
+func (r *FileRuleRepository) snapshot() (string, error) {
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read rule directory: %w", err)
+	}
+
+	var sb strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ruleFileExt {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+
+	return sb.String(), nil
+}
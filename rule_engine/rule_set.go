@@ -0,0 +1,79 @@
+package rule_engine
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RuleDefinition is the source form of a rule
+type RuleDefinition struct {
+	Key        string `json:"key"`
+	Expression string `json:"expression"`
+}
+
+// RuleSetDefinition is a versioned set of rule definitions
+type RuleSetDefinition struct {
+	Version string           `json:"version"`
+	Rules   []RuleDefinition `json:"rules"`
+}
+
+// RuleSet is a compiled, immutable RuleSetDefinition
+type RuleSet struct {
+	Version     string
+	Definitions map[string]RuleDefinition
+	Rules       map[string]NodeIf
+}
+
+// CompileRuleSet compiles every rule of the definition, failing on the first invalid one
+func CompileRuleSet(definition *RuleSetDefinition) (*RuleSet, error) {
+	if definition == nil {
+		return nil, fmt.Errorf("rule set definition is nil")
+	}
+
+	ruleSet := &RuleSet{
+		Version:     definition.Version,
+		Definitions: make(map[string]RuleDefinition, len(definition.Rules)),
+		Rules:       make(map[string]NodeIf, len(definition.Rules)),
+	}
+
+	for _, rule := range definition.Rules {
+		if _, exists := ruleSet.Rules[rule.Key]; exists {
+			return nil, fmt.Errorf("rule set %s: duplicate rule %s", definition.Version, rule.Key)
+		}
+
+		node, err := Compile(rule.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("rule set %s: compile rule %s: %w", definition.Version, rule.Key, err)
+		}
+
+		ruleSet.Definitions[rule.Key] = rule
+		ruleSet.Rules[rule.Key] = node
+	}
+
+	return ruleSet, nil
+}
+
+// GetRule returns the compiled rule by key
+func (r *RuleSet) GetRule(key string) (NodeIf, bool) {
+	node, ok := r.Rules[key]
+	return node, ok
+}
+
+// GetKeys returns the sorted keys of all rules
+func (r *RuleSet) GetKeys() []string {
+	keys := make([]string, 0, len(r.Rules))
+	for key := range r.Rules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Evaluate evaluates the rule by key against an environment
+func (r *RuleSet) Evaluate(key string, env *Environment) (ValueIf, error) {
+	node, ok := r.GetRule(key)
+	if !ok {
+		return nil, fmt.Errorf("rule set %s: undefined rule %s", r.Version, key)
+	}
+	return node.EvaluateWithEnv(env)
+}
@@ -0,0 +1,56 @@
+package rule_engine
+
+import "fmt"
+
+// TraceStep records the value of one sub-expression, literals are left out
+type TraceStep struct {
+	Expression string      `json:"expression"`
+	Value      interface{} `json:"value,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Trace explains an evaluation: every variable, call and operation with its value, innermost first
+type Trace struct {
+	Expression string       `json:"expression"`
+	Result     interface{}  `json:"result,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	Steps      []*TraceStep `json:"steps"`
+}
+
+// EvaluateWithTrace evaluates the node like Evaluate and records how the result was obtained
+func EvaluateWithTrace(node NodeIf, env *Environment) (ValueIf, *Trace, error) {
+	trace := &Trace{Expression: printForTrace(node)}
+
+	result, err := evaluateNode(node, env, trace)
+	if err != nil {
+		trace.Error = err.Error()
+		return nil, trace, err
+	}
+
+	trace.Result = getValue(result)
+	return result, trace, nil
+}
+
+// addStep records the node to the trace, nothing is recorded on a nil trace
+func (t *Trace) addStep(node NodeIf, result ValueIf, err error) {
+	if t == nil {
+		return
+	}
+
+	step := &TraceStep{Expression: printForTrace(node)}
+	if err != nil {
+		step.Error = err.Error()
+	} else if result != nil {
+		step.Value = getValue(result)
+	}
+	t.Steps = append(t.Steps, step)
+}
+
+// printForTrace prints the node, or describes it if it cannot be printed
+func printForTrace(node NodeIf) string {
+	expr, err := ToExpression(node)
+	if err != nil {
+		return fmt.Sprintf("<%s node>", node.GetType())
+	}
+	return expr
+}
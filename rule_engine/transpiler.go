@@ -0,0 +1,81 @@
+package rule_engine
+
+import "fmt"
+
+// Transpiler targets
+const (
+	TranspileTargetSQL   = "sql"
+	TranspileTargetMongo = "mongo"
+)
+
+// PushdownError reports a construct of a rule that cannot be translated to a storage query
+type PushdownError struct {
+	Target    string
+	Construct string
+	Reason    string
+}
+
+func (e *PushdownError) Error() string {
+	return fmt.Sprintf("cannot push %s down to %s: %s", e.Construct, e.Target, e.Reason)
+}
+
+func newPushdownError(target, construct, reason string) error {
+	return &PushdownError{Target: target, Construct: construct, Reason: reason}
+}
+
+// asNodeBase returns the node as a *NodeBase, transpilers only understand the built-in node tree
+func asNodeBase(target string, node NodeIf) (*NodeBase, error) {
+	n, ok := node.(*NodeBase)
+	if !ok || n == nil {
+		return nil, newPushdownError(target, fmt.Sprintf("node %T", node), "unknown node implementation")
+	}
+	return n, nil
+}
+
+// getNodeVariable returns the variable path if the node is a variable reference
+func getNodeVariable(node NodeIf) (string, bool) {
+	return GetVariableName(node)
+}
+
+// getNodeLiteral returns the value if the node is a literal, arrays of literals included
+func getNodeLiteral(node NodeIf) (interface{}, bool) {
+	n, ok := node.(*NodeBase)
+	if !ok || n == nil {
+		return nil, false
+	}
+
+	switch n.GetType() {
+	case NodeTypeValue:
+		return getValue(n.Value), true
+
+	case NodeTypeArray:
+		array := make([]interface{}, 0, len(n.PostNodeList))
+		for _, element := range n.PostNodeList {
+			value, ok := getNodeLiteral(element)
+			if !ok {
+				return nil, false
+			}
+			array = append(array, value)
+		}
+		return array, true
+
+	default:
+		return nil, false
+	}
+}
+
+// flipComparison returns the operator to use when swapping the operands of a comparison
+func flipComparison(operator string) string {
+	switch operator {
+	case OpTypeLessThan:
+		return OpTypeGreaterThan
+	case OpTypeLessEqual:
+		return OpTypeGreaterEqual
+	case OpTypeGreaterThan:
+		return OpTypeLessThan
+	case OpTypeGreaterEqual:
+		return OpTypeLessEqual
+	default:
+		return operator
+	}
+}
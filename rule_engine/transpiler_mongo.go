@@ -0,0 +1,155 @@
+package rule_engine
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var mongoOperators = map[string]string{
+	OpTypeEqual:        "$eq",
+	OpTypeNotEqual:     "$ne",
+	OpTypeLessThan:     "$lt",
+	OpTypeLessEqual:    "$lte",
+	OpTypeGreaterThan:  "$gt",
+	OpTypeGreaterEqual: "$gte",
+}
+
+// ToMongoFilter transpiles a compiled rule into a MongoDB filter document. Variable paths are used
+// as field paths. The result is a plain map, so it can be passed wherever a bson.M is expected.
+// A *PushdownError is returned for constructs without a filter equivalent, such as function calls.
+func ToMongoFilter(node NodeIf) (map[string]interface{}, error) {
+	return mongoCondition(node)
+}
+
+func mongoCondition(node NodeIf) (map[string]interface{}, error) {
+	n, err := asNodeBase(TranspileTargetMongo, node)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.GetType() {
+	case NodeTypeVariable:
+		path, _ := getNodeVariable(n)
+		return map[string]interface{}{path: true}, nil
+
+	case NodeTypeValue:
+		if b, ok := getValue(n.Value).(bool); ok {
+			if b {
+				return map[string]interface{}{}, nil
+			}
+			return map[string]interface{}{"$expr": false}, nil
+		}
+		return nil, newPushdownError(TranspileTargetMongo, fmt.Sprintf("literal %v", getValue(n.Value)), "not a condition")
+
+	case NodeTypeExpr:
+		operator := n.Operator.GetType()
+		switch operator {
+		case OpTypeAnd, OpTypeOr:
+			key := "$and"
+			if operator == OpTypeOr {
+				key = "$or"
+			}
+
+			clauses := make([]interface{}, 0, len(n.PostNodeList))
+			for _, child := range n.PostNodeList {
+				clause, err := mongoCondition(child)
+				if err != nil {
+					return nil, err
+				}
+
+				// Flatten nested operators of the same kind, a && b && c is parsed as (a && b) && c
+				if nested, ok := clause[key].([]interface{}); ok && len(clause) == 1 {
+					clauses = append(clauses, nested...)
+				} else {
+					clauses = append(clauses, clause)
+				}
+			}
+			return map[string]interface{}{key: clauses}, nil
+
+		case OpTypeNot:
+			operand, err := mongoCondition(n.PostNodeList[0])
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"$nor": []interface{}{operand}}, nil
+
+		case OpTypeEqual, OpTypeNotEqual, OpTypeLessThan, OpTypeLessEqual, OpTypeGreaterThan, OpTypeGreaterEqual:
+			return mongoComparison(operator, n.PostNodeList[0], n.PostNodeList[1])
+
+		case OpTypeIn:
+			path, ok := getNodeVariable(n.PostNodeList[0])
+			if !ok {
+				return nil, newPushdownError(TranspileTargetMongo, "operator in", "left operand must be a variable")
+			}
+			value, _ := getNodeLiteral(n.PostNodeList[1])
+			items, ok := value.([]interface{})
+			if !ok {
+				return nil, newPushdownError(TranspileTargetMongo, "operator in", "right operand must be an array literal")
+			}
+			return map[string]interface{}{path: map[string]interface{}{"$in": items}}, nil
+
+		case OpTypeContains, OpTypeStartsWith, OpTypeEndsWith, OpTypeMatches:
+			return mongoRegex(operator, n.PostNodeList[0], n.PostNodeList[1])
+		}
+
+		return nil, newPushdownError(TranspileTargetMongo, "operator "+operator, "not a condition")
+
+	case NodeTypeCall:
+		name, _ := n.Params[NodeParamName].(string)
+		return nil, newPushdownError(TranspileTargetMongo, "function "+name, "functions are evaluated in Go")
+
+	default:
+		return nil, newPushdownError(TranspileTargetMongo, "node "+n.GetType(), "not a condition")
+	}
+}
+
+// mongoComparison renders field-to-value comparisons as query operators and field-to-field ones as $expr
+func mongoComparison(operator string, left, right NodeIf) (map[string]interface{}, error) {
+	leftPath, leftIsVariable := getNodeVariable(left)
+	rightPath, rightIsVariable := getNodeVariable(right)
+	leftValue, leftIsLiteral := getNodeLiteral(left)
+	rightValue, rightIsLiteral := getNodeLiteral(right)
+
+	switch {
+	case leftIsVariable && rightIsLiteral:
+		return map[string]interface{}{leftPath: map[string]interface{}{mongoOperators[operator]: rightValue}}, nil
+	case leftIsLiteral && rightIsVariable:
+		return map[string]interface{}{rightPath: map[string]interface{}{mongoOperators[flipComparison(operator)]: leftValue}}, nil
+	case leftIsVariable && rightIsVariable:
+		return map[string]interface{}{"$expr": map[string]interface{}{
+			mongoOperators[operator]: []interface{}{"$" + leftPath, "$" + rightPath},
+		}}, nil
+	default:
+		return nil, newPushdownError(TranspileTargetMongo, "operator "+operator, "operands must be variables or literals")
+	}
+}
+
+func mongoRegex(operator string, left, right NodeIf) (map[string]interface{}, error) {
+	path, ok := getNodeVariable(left)
+	if !ok {
+		return nil, newPushdownError(TranspileTargetMongo, "operator "+operator, "left operand must be a variable")
+	}
+
+	value, _ := getNodeLiteral(right)
+	str, ok := value.(string)
+	if !ok {
+		return nil, newPushdownError(TranspileTargetMongo, "operator "+operator, "right operand must be a string literal")
+	}
+
+	var pattern string
+	switch operator {
+	case OpTypeContains:
+		pattern = regexp.QuoteMeta(str)
+	case OpTypeStartsWith:
+		pattern = "^" + regexp.QuoteMeta(str)
+	case OpTypeEndsWith:
+		pattern = regexp.QuoteMeta(str) + "$"
+	case OpTypeMatches:
+		if _, err := regexp.Compile(str); err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", str, err)
+		}
+		pattern = str
+	}
+
+	return map[string]interface{}{path: map[string]interface{}{"$regex": pattern}}, nil
+}
@@ -0,0 +1,287 @@
+package rule_engine
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SQLOptions controls how a rule is rendered as a SQL WHERE fragment
+type SQLOptions struct {
+	// Placeholder renders the n-th (1-based) bind parameter, defaults to QuestionPlaceholder
+	Placeholder func(n int) string
+	// Column maps a variable path to a column expression, defaults to the path itself
+	Column func(path string) (string, error)
+}
+
+// QuestionPlaceholder renders bind parameters as ? (MySQL, SQLite)
+func QuestionPlaceholder(n int) string {
+	return "?"
+}
+
+// DollarPlaceholder renders bind parameters as $1, $2, ... (PostgreSQL)
+func DollarPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+var sqlColumnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// sqlLikeEscaper escapes the LIKE wildcards with !, which unlike \ needs no quoting in any dialect
+// (MySQL reads '\' as an unterminated string)
+var sqlLikeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+var sqlOperators = map[string]string{
+	OpTypeEqual:        "=",
+	OpTypeNotEqual:     "<>",
+	OpTypeLessThan:     "<",
+	OpTypeLessEqual:    "<=",
+	OpTypeGreaterThan:  ">",
+	OpTypeGreaterEqual: ">=",
+	OpTypeAdd:          "+",
+	OpTypeSubtract:     "-",
+	OpTypeMultiply:     "*",
+	OpTypeDivide:       "/",
+	OpTypeMod:          "%",
+}
+
+// ToSQL transpiles a compiled rule into a parameterized SQL WHERE fragment and its bind arguments.
+// Literals are always passed as arguments, never inlined. A *PushdownError is returned for
+// constructs without a portable SQL equivalent, such as function calls and regular expressions.
+func ToSQL(node NodeIf, options *SQLOptions) (string, []interface{}, error) {
+	t := &sqlTranspiler{options: SQLOptions{Placeholder: QuestionPlaceholder, Column: defaultSQLColumn}}
+	if options != nil && options.Placeholder != nil {
+		t.options.Placeholder = options.Placeholder
+	}
+	if options != nil && options.Column != nil {
+		t.options.Column = options.Column
+	}
+
+	clause, err := t.condition(node)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return clause, t.args, nil
+}
+
+func defaultSQLColumn(path string) (string, error) {
+	if !sqlColumnPattern.MatchString(path) {
+		return "", newPushdownError(TranspileTargetSQL, "variable "+path, "not a valid column name")
+	}
+	return path, nil
+}
+
+type sqlTranspiler struct {
+	options SQLOptions
+	args    []interface{}
+}
+
+func (t *sqlTranspiler) bind(value interface{}) string {
+	t.args = append(t.args, value)
+	return t.options.Placeholder(len(t.args))
+}
+
+// condition renders a node that must yield a boolean
+func (t *sqlTranspiler) condition(node NodeIf) (string, error) {
+	n, err := asNodeBase(TranspileTargetSQL, node)
+	if err != nil {
+		return "", err
+	}
+
+	switch n.GetType() {
+	case NodeTypeVariable:
+		path, _ := getNodeVariable(n)
+		return t.options.Column(path)
+
+	case NodeTypeValue:
+		if b, ok := getValue(n.Value).(bool); ok {
+			if b {
+				return "1 = 1", nil
+			}
+			return "1 = 0", nil
+		}
+		return "", newPushdownError(TranspileTargetSQL, fmt.Sprintf("literal %v", getValue(n.Value)), "not a condition")
+
+	case NodeTypeExpr:
+		operator := n.Operator.GetType()
+		switch operator {
+		case OpTypeAnd, OpTypeOr:
+			left, err := t.condition(n.PostNodeList[0])
+			if err != nil {
+				return "", err
+			}
+			right, err := t.condition(n.PostNodeList[1])
+			if err != nil {
+				return "", err
+			}
+			keyword := "AND"
+			if operator == OpTypeOr {
+				keyword = "OR"
+			}
+			return fmt.Sprintf("(%s %s %s)", left, keyword, right), nil
+
+		case OpTypeNot:
+			operand, err := t.condition(n.PostNodeList[0])
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("NOT (%s)", operand), nil
+
+		case OpTypeEqual, OpTypeNotEqual:
+			return t.equal(n)
+
+		case OpTypeLessThan, OpTypeLessEqual, OpTypeGreaterThan, OpTypeGreaterEqual:
+			left, err := t.operand(n.PostNodeList[0])
+			if err != nil {
+				return "", err
+			}
+			right, err := t.operand(n.PostNodeList[1])
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s %s %s", left, sqlOperators[operator], right), nil
+
+		case OpTypeIn:
+			return t.in(n)
+
+		case OpTypeContains, OpTypeStartsWith, OpTypeEndsWith:
+			return t.like(n)
+
+		case OpTypeMatches:
+			return "", newPushdownError(TranspileTargetSQL, "operator "+operator, "regular expression syntax is dialect specific")
+		}
+
+		return "", newPushdownError(TranspileTargetSQL, "operator "+operator, "not a condition")
+
+	case NodeTypeCall:
+		name, _ := n.Params[NodeParamName].(string)
+		return "", newPushdownError(TranspileTargetSQL, "function "+name, "functions are evaluated in Go")
+
+	default:
+		return "", newPushdownError(TranspileTargetSQL, "node "+n.GetType(), "not a condition")
+	}
+}
+
+// operand renders a node used as a value: a column, a bind parameter or an arithmetic expression
+func (t *sqlTranspiler) operand(node NodeIf) (string, error) {
+	if path, ok := getNodeVariable(node); ok {
+		return t.options.Column(path)
+	}
+	if value, ok := getNodeLiteral(node); ok {
+		if _, isArray := value.([]interface{}); isArray {
+			return "", newPushdownError(TranspileTargetSQL, "array literal", "only supported on the right of in")
+		}
+		return t.bind(value), nil
+	}
+
+	n, err := asNodeBase(TranspileTargetSQL, node)
+	if err != nil {
+		return "", err
+	}
+
+	if n.GetType() == NodeTypeExpr {
+		operator := n.Operator.GetType()
+		switch operator {
+		case OpTypeAdd, OpTypeSubtract, OpTypeMultiply, OpTypeDivide, OpTypeMod:
+			left, err := t.operand(n.PostNodeList[0])
+			if err != nil {
+				return "", err
+			}
+			right, err := t.operand(n.PostNodeList[1])
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("(%s %s %s)", left, sqlOperators[operator], right), nil
+		}
+	}
+
+	// Boolean sub-expressions compared with a value, e.g. (a > 1) == true
+	return t.condition(node)
+}
+
+// equal renders == and !=, comparing with null as IS NULL since = NULL is never true in SQL
+func (t *sqlTranspiler) equal(n *NodeBase) (string, error) {
+	left, right := n.PostNodeList[0], n.PostNodeList[1]
+	if isNullLiteral(left) {
+		left, right = right, left
+	}
+
+	column, err := t.operand(left)
+	if err != nil {
+		return "", err
+	}
+
+	if isNullLiteral(right) {
+		if n.Operator.GetType() == OpTypeNotEqual {
+			return column + " IS NOT NULL", nil
+		}
+		return column + " IS NULL", nil
+	}
+
+	value, err := t.operand(right)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s %s", column, sqlOperators[n.Operator.GetType()], value), nil
+}
+
+func isNullLiteral(node NodeIf) bool {
+	value, ok := getNodeLiteral(node)
+	return ok && value == nil
+}
+
+func (t *sqlTranspiler) in(n *NodeBase) (string, error) {
+	column, err := t.operand(n.PostNodeList[0])
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := getNodeLiteral(n.PostNodeList[1])
+	items, isArray := value.([]interface{})
+	if !ok || !isArray {
+		return "", newPushdownError(TranspileTargetSQL, "operator in", "right operand must be an array literal")
+	}
+
+	if len(items) == 0 {
+		return "1 = 0", nil
+	}
+
+	placeholders := make([]string, len(items))
+	for i, item := range items {
+		placeholders[i] = t.bind(item)
+	}
+
+	return fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", ")), nil
+}
+
+func (t *sqlTranspiler) like(n *NodeBase) (string, error) {
+	operator := n.Operator.GetType()
+
+	path, ok := getNodeVariable(n.PostNodeList[0])
+	if !ok {
+		return "", newPushdownError(TranspileTargetSQL, "operator "+operator, "left operand must be a variable")
+	}
+	column, err := t.options.Column(path)
+	if err != nil {
+		return "", err
+	}
+
+	value, _ := getNodeLiteral(n.PostNodeList[1])
+	str, ok := value.(string)
+	if !ok {
+		return "", newPushdownError(TranspileTargetSQL, "operator "+operator, "right operand must be a string literal")
+	}
+
+	pattern := sqlLikeEscaper.Replace(str)
+	switch operator {
+	case OpTypeContains:
+		pattern = "%" + pattern + "%"
+	case OpTypeStartsWith:
+		pattern = pattern + "%"
+	case OpTypeEndsWith:
+		pattern = "%" + pattern
+	}
+
+	return fmt.Sprintf(`%s LIKE %s ESCAPE '!'`, column, t.bind(pattern)), nil
+}
@@ -0,0 +1,52 @@
+package rule_engine
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestToSQL(t *testing.T) {
+	tests := []struct {
+		expr string
+		sql  string
+		args []interface{}
+	}{
+		{"age >= 18 && country == \"CN\"", "(age >= ? AND country = ?)", []interface{}{18, "CN"}},
+		{"deleted_at == null", "deleted_at IS NULL", nil},
+		{"deleted_at != null", "deleted_at IS NOT NULL", nil},
+		{"null == deleted_at", "deleted_at IS NULL", nil},
+		{"!(user.name != null)", "NOT (user.name IS NOT NULL)", nil},
+		{"price * 2 < 100", "(price * ?) < ?", []interface{}{2, 100}},
+		{"status in [\"a\", \"b\"]", "status IN (?, ?)", []interface{}{"a", "b"}},
+		{"name contains \"50%\"", "name LIKE ? ESCAPE '!'", []interface{}{"%50!%%"}},
+	}
+
+	for _, test := range tests {
+		node, err := Compile(test.expr)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", test.expr, err)
+		}
+
+		sql, args, err := ToSQL(node, nil)
+		if err != nil {
+			t.Errorf("ToSQL(%q): %v", test.expr, err)
+			continue
+		}
+		if sql != test.sql || !reflect.DeepEqual(args, test.args) {
+			t.Errorf("ToSQL(%q) = %q %v, want %q %v", test.expr, sql, args, test.sql, test.args)
+		}
+	}
+}
+
+func TestToSQLPushdownError(t *testing.T) {
+	node, err := Compile("name matches \"^a\"")
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	var pushdown *PushdownError
+	if _, _, err := ToSQL(node, nil); !errors.As(err, &pushdown) {
+		t.Errorf("ToSQL error = %v, want a *PushdownError", err)
+	}
+}
@@ -24,6 +24,7 @@ const (
 	ValueTypeByte       = "byte" // alias for uint8
 	ValueTypeRune       = "rune" // alias for int32
 	ValueTypeString     = "string"
+	ValueTypeTime       = "time"
 	ValueTypeArray      = "array" // Composite ValueTypes
 	ValueTypeSet        = "set"
 	ValueTypeMap        = "map"
@@ -32,6 +33,12 @@ const (
 	ValueTypeFunc       = "func"
 )
 
+// MemberValueIf is a value whose fields, keys, elements or methods can be accessed with a dot in expressions
+type MemberValueIf interface {
+	ValueIf
+	GetMember(name string) (ValueIf, bool)
+}
+
 type ValueBase struct {
 	Type  string
 	ID    string
@@ -49,3 +56,8 @@ func (v *ValueBase) GetValue() interface{} {
 func (v *ValueBase) SetValue(value interface{}) {
 	v.Value = value
 }
+
+// NewValue wraps a Go value into a ValueIf, inferring its type
+func NewValue(value interface{}) ValueIf {
+	return &ValueBase{Type: getValueType(value), Value: value}
+}